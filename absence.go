@@ -0,0 +1,251 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrTreeNotSorted is returned when absence is to be proven for a tree whose leaves are not sorted.
+	ErrTreeNotSorted = errors.New("error: tree leaves are not sorted")
+	// ErrSortOrder is returned when a change to a sorted tree would put its leaves out of order.
+	ErrSortOrder = errors.New("error: change breaks the sort order of the tree leaves")
+	// ErrPayloadPresent is returned when absence is to be proven for a payload whose sort key is the
+	// key of a leaf of the tree.
+	ErrPayloadPresent = errors.New("error: payload key present in tree")
+)
+
+// AbsenceProof proves that a payload is not part of a sorted tree. It holds the inclusion proofs of the
+// two adjacent leaves the payload would be placed between. The left neighbour is missing if the payload
+// would be placed before the first leaf and the right neighbour is missing if it would be placed after
+// the last leaf. The neighbours of a tree created with NewTreeFromHashes hold no payload and are proven by
+// the leaf hashes of their inclusion proofs.
+type AbsenceProof struct {
+	// Left is the inclusion proof of the left neighbour or nil if there is none.
+	Left *Proof
+	// LeftPayload is the payload of the left neighbour or nil if there is none or it holds no payload.
+	LeftPayload Payload
+	// Right is the inclusion proof of the right neighbour or nil if there is none.
+	Right *Proof
+	// RightPayload is the payload of the right neighbour or nil if there is none or it holds no payload.
+	RightPayload Payload
+}
+
+// ProveAbsence builds an AbsenceProof for the given item. Returns ErrTreeNotSorted if the tree was not
+// created with WithSortedLeaves or WithSortKey and ErrPayloadPresent if a leaf has the sort key of the item.
+func (m *Tree[T]) ProveAbsence(item T) (*AbsenceProof, error) {
+	if !m.config.sorted {
+		return nil, ErrTreeNotSorted
+	}
+
+	key, err := m.config.sortKeyOf(m.HashFunc, item)
+	if err != nil {
+		return nil, err
+	}
+
+	size := m.leafCount()
+
+	var keyErr error
+
+	pos := sort.Search(size, func(i int) bool {
+		leafKey, err := m.leafSortKey(m.Leafs[i])
+		if err != nil {
+			keyErr = err
+			return true
+		}
+
+		return bytes.Compare(leafKey, key) >= 0
+	})
+
+	if keyErr != nil {
+		return nil, keyErr
+	}
+
+	if pos < size {
+		leafKey, err := m.leafSortKey(m.Leafs[pos])
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Equal(leafKey, key) {
+			return nil, ErrPayloadPresent
+		}
+	}
+
+	proof := &AbsenceProof{}
+
+	if pos > 0 {
+		proof.Left = m.proofForLeaf(pos - 1)
+		proof.LeftPayload = m.Leafs[pos-1].Payload
+	}
+
+	if pos < size {
+		proof.Right = m.proofForLeaf(pos)
+		proof.RightPayload = m.Leafs[pos].Payload
+	}
+
+	return proof, nil
+}
+
+// VerifyAbsence checks that the payload is not part of a sorted tree with the given merkle root and number
+// of leaves. Both neighbours have to be part of the tree, be adjacent and have sort keys enclosing the sort
+// key of the payload. A missing neighbour has to be made up for by the other one being the first or the last
+// leaf. The tree size has to be known to the verifier, since the proofs of the neighbours are only checked
+// against it. The options have to match the ones the tree was created with. Returns true if valid and false
+// otherwise.
+func VerifyAbsence(
+	root []byte, treeSize int, payload Payload, proof AbsenceProof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	if !c.sorted {
+		return false, ErrTreeNotSorted
+	}
+
+	if err := rejectHashPayload(payload); err != nil {
+		return false, err
+	}
+
+	key, err := c.sortKeyOf(hashFunc, payload)
+	if err != nil {
+		return false, err
+	}
+
+	left, right := proof.Left, proof.Right
+
+	switch {
+	case left == nil && right == nil:
+		return false, nil
+	case left != nil && left.TreeSize != treeSize, right != nil && right.TreeSize != treeSize:
+		return false, nil
+	case left == nil && right.LeafIndex != 0:
+		return false, nil
+	case right == nil && left.LeafIndex != treeSize-1:
+		return false, nil
+	case left != nil && right != nil && right.LeafIndex != left.LeafIndex+1:
+		return false, nil
+	}
+
+	if left != nil {
+		ok, err := verifyNeighbour(root, treeSize, proof.LeftPayload, *left, hashFunc, c, opts, func(k []byte) bool {
+			return bytes.Compare(k, key) < 0
+		})
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	if right != nil {
+		ok, err := verifyNeighbour(root, treeSize, proof.RightPayload, *right, hashFunc, c, opts, func(k []byte) bool {
+			return bytes.Compare(k, key) > 0
+		})
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// verifyNeighbour checks the inclusion proof of a neighbour of an absent payload and that the sort key
+// of the neighbour is on the expected side of the key of the payload. A neighbour given as a HashPayload
+// is not valid and one without a payload is proven by its leaf hash, which is its sort key, see VerifyLeafHash.
+func verifyNeighbour(root []byte, treeSize int, payload Payload, proof Proof, hashFunc HashFunc, c treeConfig,
+	opts []Option, ordered func(key []byte) bool) (bool, error) {
+	if _, ok := payload.(HashPayload); ok {
+		return false, nil
+	}
+
+	if payload == nil {
+		if proof.LeafHash == nil || c.sortKey != nil || !ordered(proof.LeafHash) {
+			return false, nil
+		}
+
+		return VerifyLeafHash(root, proof.LeafHash, treeSize, proof, hashFunc, opts...)
+	}
+
+	key, err := c.sortKeyOf(hashFunc, payload)
+	if err != nil {
+		return false, err
+	}
+
+	if !ordered(key) {
+		return false, nil
+	}
+
+	return VerifyProof(root, payload, proof, hashFunc, opts...)
+}
+
+// leafSortKey returns the key the given leaf is ordered by in a sorted tree.
+func (m *BaseTree) leafSortKey(n *Node) ([]byte, error) {
+	if m.config.sortKey == nil {
+		return n.Hash, nil
+	}
+
+	if n.hashOnly() {
+		return nil, errors.New("error: cannot calculate sort key of leaf without payload")
+	}
+
+	return m.config.sortKey(n.Payload)
+}
+
+// sortLeafNodes orders the leaf nodes by their sort key if the tree is sorted.
+func sortLeafNodes(leafNodes []*Node, tree *BaseTree) error {
+	if !tree.config.sorted {
+		return nil
+	}
+
+	keys := make(map[*Node][]byte, len(leafNodes))
+
+	for _, n := range leafNodes {
+		key, err := tree.leafSortKey(n)
+		if err != nil {
+			return err
+		}
+
+		keys[n] = key
+	}
+
+	sort.SliceStable(leafNodes, func(i, j int) bool {
+		return bytes.Compare(keys[leafNodes[i]], keys[leafNodes[j]]) < 0
+	})
+
+	return nil
+}
+
+// checkSortOrder checks that the leaf nodes from position from to position to, both included, are not
+// ordered before their left neighbours if the tree is sorted. Returns ErrSortOrder otherwise.
+func (m *BaseTree) checkSortOrder(leafNodes []*Node, from, to int) error {
+	if !m.config.sorted {
+		return nil
+	}
+
+	if from < 1 {
+		from = 1
+	}
+
+	if to > len(leafNodes)-1 {
+		to = len(leafNodes) - 1
+	}
+
+	for i := from; i <= to; i++ {
+		prev, err := m.leafSortKey(leafNodes[i-1])
+		if err != nil {
+			return err
+		}
+
+		key, err := m.leafSortKey(leafNodes[i])
+		if err != nil {
+			return err
+		}
+
+		if bytes.Compare(prev, key) > 0 {
+			return ErrSortOrder
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func senderSortKey(p merkletree.Payload) ([]byte, error) {
+	return []byte(p.(merkletree.PaymentTransactionPayload).SenderAddress), nil
+}
+
+var sortModes = []struct {
+	name    string
+	option  merkletree.Option
+	leafKey func(n *merkletree.Node) []byte
+}{
+	{
+		name:    "by hash",
+		option:  merkletree.WithSortedLeaves(),
+		leafKey: func(n *merkletree.Node) []byte { return n.Hash },
+	},
+	{
+		name:   "by sender",
+		option: merkletree.WithSortKey(senderSortKey),
+		leafKey: func(n *merkletree.Node) []byte {
+			return []byte(n.Payload.(merkletree.PaymentTransactionPayload).SenderAddress)
+		},
+	},
+}
+
+func absentPayloads() []merkletree.Payload {
+	return []merkletree.Payload{
+		merkletree.PaymentTransactionPayload{SenderAddress: "1", ReceiverAddress: "1", Amount: 1},
+		merkletree.PaymentTransactionPayload{SenderAddress: "mn", ReceiverAddress: "mn", Amount: 1},
+		merkletree.PaymentTransactionPayload{SenderAddress: "zz", ReceiverAddress: "zz", Amount: 1},
+		inputs[1].invalidPayload,
+	}
+}
+
+func TestNewTreeSortedLeaves(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		for _, test := range inputs {
+			for _, mode := range sortModes {
+				tree, err := merkletree.NewTree(
+					test.payloads, merkletree.SHA256(), merkletree.WithOddNodeStrategy(strategy), mode.option)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for i := 1; i < len(test.payloads); i++ {
+					if bytes.Compare(mode.leafKey(tree.Leafs[i-1]), mode.leafKey(tree.Leafs[i])) > 0 {
+						t.Errorf("[test case: %s] error: expected leaf %d to be sorted %s", test.testCaseName, i, mode.name)
+					}
+				}
+
+				ok, err := tree.VerifyTree()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !ok {
+					t.Errorf("[test case: %s] error: expected sorted tree to be valid", test.testCaseName)
+				}
+
+				for _, payload := range test.payloads {
+					verifyValidPayload(t, tree, test.testCaseName, payload)
+				}
+			}
+		}
+	}
+}
+
+func TestMerkleTreeProveAbsence(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		for _, test := range inputs {
+			for _, mode := range sortModes {
+				opts := []merkletree.Option{merkletree.WithOddNodeStrategy(strategy), mode.option}
+
+				tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				otherRoot := append([]byte{}, tree.MerkleRootHash...)
+				otherRoot[0] ^= 0xff
+
+				for _, payload := range absentPayloads() {
+					proof, err := tree.ProveAbsence(payload)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					verifyAbsence(t, test.testCaseName, tree.MerkleRootHash, len(test.payloads), payload, proof, true, opts...)
+					verifyAbsence(t, test.testCaseName, otherRoot, len(test.payloads), payload, proof, false, opts...)
+				}
+
+				for _, payload := range test.payloads {
+					if _, err := tree.ProveAbsence(payload); !errors.Is(err, merkletree.ErrPayloadPresent) {
+						t.Errorf("[test case: %s] error: expected ErrPayloadPresent, got %v", test.testCaseName, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMerkleTreeProveAbsenceFromHashes(t *testing.T) {
+	sortOpt := merkletree.WithSortedLeaves()
+
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), sortOpt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var hashes [][]byte
+
+		for _, n := range tree.Leafs[:len(test.payloads)] {
+			hashes = append(hashes, n.Hash)
+		}
+
+		hashTree, err := merkletree.NewTreeFromHashes(hashes, merkletree.SHA256(), sortOpt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, payload := range absentPayloads() {
+			proof, err := hashTree.ProveAbsence(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proof.LeftPayload != nil || proof.RightPayload != nil {
+				t.Errorf("[test case: %s] error: expected neighbours without payloads", test.testCaseName)
+			}
+
+			verifyAbsence(t, test.testCaseName, hashTree.MerkleRootHash, len(hashes), payload, proof, true, sortOpt)
+			verifyAbsence(t, test.testCaseName, hashTree.MerkleRootHash, len(hashes)+1, payload, proof, false, sortOpt)
+		}
+	}
+}
+
+func TestMerkleTreeProveAbsenceUnsortedTree(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.ProveAbsence(inputs[0].invalidPayload); !errors.Is(err, merkletree.ErrTreeNotSorted) {
+		t.Errorf("error: expected ErrTreeNotSorted, got %v", err)
+	}
+}
+
+func TestVerifyAbsenceOfPresentPayload(t *testing.T) {
+	sortOpt := merkletree.WithSortKey(senderSortKey)
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), sortOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(test.payloads)-1; i++ {
+		payload := tree.Leafs[i].Payload
+
+		left, err := tree.ProofByIndex(i - 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		right, err := tree.ProofByIndex(i + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		skipping := &merkletree.AbsenceProof{
+			Left:         left,
+			LeftPayload:  tree.Leafs[i-1].Payload,
+			Right:        right,
+			RightPayload: tree.Leafs[i+1].Payload,
+		}
+		verifyAbsence(t, test.testCaseName, tree.MerkleRootHash, len(test.payloads), payload, skipping, false, sortOpt)
+
+		leftOnly := &merkletree.AbsenceProof{Left: left, LeftPayload: tree.Leafs[i-1].Payload}
+		verifyAbsence(t, test.testCaseName, tree.MerkleRootHash, len(test.payloads), payload, leftOnly, false, sortOpt)
+
+		swapped := &merkletree.AbsenceProof{
+			Left:         right,
+			LeftPayload:  tree.Leafs[i+1].Payload,
+			Right:        left,
+			RightPayload: tree.Leafs[i-1].Payload,
+		}
+		verifyAbsence(t, test.testCaseName, tree.MerkleRootHash, len(test.payloads), payload, swapped, false, sortOpt)
+	}
+}
+
+func TestVerifyAbsenceInnerNodeForgery(t *testing.T) {
+	sortOpt := merkletree.WithSortedLeaves()
+	payload := merkletree.StringPayload("p2")
+
+	tree, err := merkletree.NewTree([]merkletree.StringPayload{"p0", "p1", payload, "p3"}, merkletree.SHA256(), sortOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.ProveAbsence(payload); !errors.Is(err, merkletree.ErrPayloadPresent) {
+		t.Fatalf("error: expected ErrPayloadPresent, got %v", err)
+	}
+
+	// The inner nodes claimed as the leaves of a tree of two leaves, enclosing the leaf hash of the payload.
+	left, right := tree.Root.Left.Hash, tree.Root.Right.Hash
+	forged := &merkletree.AbsenceProof{
+		Left:         &merkletree.Proof{LeafIndex: 0, TreeSize: 2, Siblings: [][]byte{right}, Directions: []int64{1}},
+		LeftPayload:  merkletree.HashPayload(left),
+		Right:        &merkletree.Proof{LeafIndex: 1, TreeSize: 2, Siblings: [][]byte{left}, Directions: []int64{0}},
+		RightPayload: merkletree.HashPayload(right),
+	}
+
+	verifyAbsence(t, "inner node forgery", tree.MerkleRootHash, 4, payload, forged, false, sortOpt)
+	verifyAbsence(t, "inner node forgery", tree.MerkleRootHash, 2, payload, forged, false, sortOpt)
+
+	// The same inner nodes given as the leaf hashes of neighbours without payloads.
+	forged.Left.LeafHash, forged.LeftPayload = left, nil
+	forged.Right.LeafHash, forged.RightPayload = right, nil
+
+	verifyAbsence(t, "inner node forgery", tree.MerkleRootHash, 4, payload, forged, false, sortOpt)
+}
+
+func TestMerkleTreeSortOrderIsKept(t *testing.T) {
+	sortOpt := merkletree.WithSortKey(senderSortKey)
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), sortOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tree.MerkleRootHash
+	first := merkletree.PaymentTransactionPayload{SenderAddress: "1", ReceiverAddress: "1", Amount: 1}
+	last := merkletree.PaymentTransactionPayload{SenderAddress: "zz", ReceiverAddress: "zz", Amount: 1}
+
+	if err := tree.Append(first); !errors.Is(err, merkletree.ErrSortOrder) {
+		t.Errorf("error: expected ErrSortOrder on append, got %v", err)
+	}
+
+	if _, err := tree.InsertLeaf(1, last); !errors.Is(err, merkletree.ErrSortOrder) {
+		t.Errorf("error: expected ErrSortOrder on insert, got %v", err)
+	}
+
+	if err := tree.UpdateLeaf(1, last); !errors.Is(err, merkletree.ErrSortOrder) {
+		t.Errorf("error: expected ErrSortOrder on update, got %v", err)
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, root) {
+		t.Error("error: expected tree to be unchanged")
+	}
+
+	if err := tree.Append(last); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.InsertLeaf(0, first); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := append([]merkletree.Payload{first}, test.payloads...)
+	assertTreeEquals(t, test.testCaseName, tree, append(updated, last), sortOpt)
+}
+
+func verifyAbsence(t *testing.T, testCaseName string, root []byte, treeSize int, payload merkletree.Payload,
+	proof *merkletree.AbsenceProof, expected bool, opts ...merkletree.Option) {
+	t.Helper()
+
+	ok, err := merkletree.VerifyAbsence(root, treeSize, payload, *proof, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("[test case: %s] error: expected absence proof of %v to be %t", testCaseName, payload, expected)
+	}
+}
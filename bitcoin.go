@@ -0,0 +1,107 @@
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// ErrMutatedMerkleTree is returned when two sibling hashes of a Bitcoin merkle tree are identical. A list of
+// transactions ending with a repeated run of transactions has the same merkle root as the list without the
+// repetition, see CVE-2012-2459, so a block with such a tree has to be rejected.
+var ErrMutatedMerkleTree = errors.New("error: merkle tree has identical sibling hashes")
+
+// doubleSHA256 is a SHA-256 hash whose digest is hashed once more with SHA-256.
+type doubleSHA256 struct {
+	hash.Hash
+}
+
+// Sum appends the SHA-256 hash of the SHA-256 digest of the data written so far to b.
+func (d doubleSHA256) Sum(b []byte) []byte {
+	digest := sha256.Sum256(d.Hash.Sum(nil))
+
+	return append(b, digest[:]...)
+}
+
+// DoubleSHA256 returns the constructor function for double SHA-256, the SHA-256 hash of the SHA-256 hash
+// of the data, as used by Bitcoin for transaction ids and merkle trees.
+func DoubleSHA256() HashFunc {
+	return func() hash.Hash {
+		return doubleSHA256{Hash: sha256.New()}
+	}
+}
+
+// ReverseBytes returns a copy of the given bytes in reverse order. Bitcoin displays transaction ids and block
+// hashes in the reverse order of the bytes it hashes.
+func ReverseBytes(b []byte) []byte {
+	reversed := make([]byte, len(b))
+
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+
+	return reversed
+}
+
+// TxidFromHex decodes a transaction id in the hex display format of block explorers and RPCs to its
+// internal byte order, the one used to build merkle trees.
+func TxidFromHex(s string) ([]byte, error) {
+	txid, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(txid) != sha256.Size {
+		return nil, fmt.Errorf("error: txid of %d bytes, expected %d", len(txid), sha256.Size)
+	}
+
+	return ReverseBytes(txid), nil
+}
+
+// TxidToHex encodes a transaction id or merkle root in internal byte order to its hex display format.
+func TxidToHex(txid []byte) string {
+	return hex.EncodeToString(ReverseBytes(txid))
+}
+
+// BitcoinMerkleRoot calculates the merkle root of a block from the ids of its transactions in internal byte
+// order, see TxidFromHex. The root is in internal byte order as well, as stored in the block header.
+// Returns ErrMutatedMerkleTree together with the root if two sibling hashes are identical.
+func BitcoinMerkleRoot(txids [][]byte) ([]byte, error) {
+	if len(txids) == 1 {
+		// The root of a block with a single transaction is its id, without pairing it with itself.
+		if len(txids[0]) != sha256.Size {
+			return nil, fmt.Errorf("error: txid of %d bytes, expected %d", len(txids[0]), sha256.Size)
+		}
+
+		return append([]byte{}, txids[0]...), nil
+	}
+
+	tree, err := NewTreeFromHashes(txids, DoubleSHA256(), WithLegacyHashing(),
+		WithOddNodeStrategy(OddNodeDuplicate))
+	if err != nil {
+		return nil, err
+	}
+
+	if hasIdenticalSiblings(tree.Root) {
+		return tree.MerkleRootHash, ErrMutatedMerkleTree
+	}
+
+	return tree.MerkleRootHash, nil
+}
+
+// hasIdenticalSiblings reports whether any two distinct children of a node below n have the same hash. The
+// copy the last node of an odd level is paired with does not count as a distinct child.
+func hasIdenticalSiblings(n *Node) bool {
+	if n == nil || n.isLeaf {
+		return false
+	}
+
+	if n.Right != n.Left && !n.Right.isDuplicate && bytes.Equal(n.Left.Hash, n.Right.Hash) {
+		return true
+	}
+
+	return hasIdenticalSiblings(n.Left) || (n.Right != n.Left && hasIdenticalSiblings(n.Right))
+}
@@ -0,0 +1,141 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// The transaction ids and merkle roots are the ones of mainnet blocks, in display byte order.
+var bitcoinBlocks = []struct {
+	testCaseName string
+	txids        []string
+	merkleRoot   string
+}{
+	{
+		testCaseName: "genesis block",
+		txids:        []string{"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+		merkleRoot:   "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+	},
+	{
+		testCaseName: "block 100000",
+		txids: []string{
+			"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+			"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+			"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+			"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+		},
+		merkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+	},
+	{
+		// Seven transactions leave the last transaction and the last node of the level above unpaired.
+		testCaseName: "block 000000000000b731f2eef9e8c63173adfb07e41bd53eb0ef0a6b720d6cb6dea4",
+		txids: []string{
+			"147caa76786596590baa4e98f5d9f48b86c7765e489f7a6ff3360fe5c674360b",
+			"0bcb16af267dee77ed8761662d31ee9d9a1bf1e4d268a9e7127407ebb3f9acfd",
+			"48738657818e2628f216375a9d48d681e2b1b1390be1b7a028c7b810eaa3928a",
+			"02981fa052f0481dbc5868f4fc2166035a10f27a03cfd2de67326471df5bc041",
+			"652b0aa4cf4f17bdb31f7a1d308331bba91f3b3cbf8f39c9cb5e19d4015b9f01",
+			"68d0685759c3d4f3f90a4f0e48d1b77641f06bb1f0b83a8841e8d71d5570ed41",
+			"0a2a92f0bda4727d0a13eaddf4dd9ac6b5c61a1429e6b2b818f19b15df0ac154",
+		},
+		merkleRoot: "8772d9d0fdf8c1303c7b1167e3c73b095fd970e33c799c6563d98b2e96c5167f",
+	},
+}
+
+func decodeTxids(t *testing.T, hexTxids []string) [][]byte {
+	txids := make([][]byte, 0, len(hexTxids))
+
+	for _, s := range hexTxids {
+		txid, err := merkletree.TxidFromHex(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		txids = append(txids, txid)
+	}
+
+	return txids
+}
+
+func TestBitcoinMerkleRoot(t *testing.T) {
+	for _, test := range bitcoinBlocks {
+		txids := decodeTxids(t, test.txids)
+
+		root, err := merkletree.BitcoinMerkleRoot(txids)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if merkletree.TxidToHex(root) != test.merkleRoot {
+			t.Errorf("[test case: %s] error: expected merkle root %s got %s",
+				test.testCaseName, test.merkleRoot, merkletree.TxidToHex(root))
+		}
+	}
+}
+
+func TestBitcoinMerkleRootMutated(t *testing.T) {
+	block := bitcoinBlocks[2]
+	txids := decodeTxids(t, block.txids)
+
+	root, err := merkletree.BitcoinMerkleRoot(txids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merkletree.TxidToHex(root) != block.merkleRoot {
+		t.Errorf("error: expected merkle root %s got %s", block.merkleRoot, merkletree.TxidToHex(root))
+	}
+
+	// Repeating the unpaired last transaction gives the root of the block.
+	mutated := append(append([][]byte{}, txids...), txids[len(txids)-1])
+
+	mutatedRoot, err := merkletree.BitcoinMerkleRoot(mutated)
+	if !errors.Is(err, merkletree.ErrMutatedMerkleTree) {
+		t.Errorf("error: expected ErrMutatedMerkleTree got %v", err)
+	}
+
+	if !bytes.Equal(mutatedRoot, root) {
+		t.Errorf("error: expected mutated merkle root %x equal to %x", mutatedRoot, root)
+	}
+
+	all := decodeTxids(t, bitcoinBlocks[1].txids)
+	six := append(append([][]byte{}, all...), all[:2]...)
+
+	root, err = merkletree.BitcoinMerkleRoot(six)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Repeating the last two transactions repeats the subtree of the odd last node of the level above.
+	mutatedRoot, err = merkletree.BitcoinMerkleRoot(append(six, all[:2]...))
+	if !errors.Is(err, merkletree.ErrMutatedMerkleTree) {
+		t.Errorf("error: expected ErrMutatedMerkleTree for repeated subtree got %v", err)
+	}
+
+	if !bytes.Equal(mutatedRoot, root) {
+		t.Errorf("error: expected mutated merkle root %x equal to %x", mutatedRoot, root)
+	}
+}
+
+func TestDoubleSHA256(t *testing.T) {
+	digest, err := merkletree.DoubleSHA256().Calculate([]byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "4f8b42c22dd3729b519ba6f68d2da7cc5b2d606d05daed5ad5128cc03e6c6358"
+	if merkletree.TxidToHex(merkletree.ReverseBytes(digest)) != expected {
+		t.Errorf("error: expected digest %s got %x", expected, digest)
+	}
+
+	if name := merkletree.DoubleSHA256().Name(); name != "sha256d" {
+		t.Errorf("error: expected name sha256d got %s", name)
+	}
+
+	if _, err := merkletree.TxidFromHex("00"); err == nil {
+		t.Error("error: expected error for txid of the wrong size")
+	}
+}
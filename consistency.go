@@ -0,0 +1,171 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ConsistencyProof returns the hashes proving that the version of the tree with oldSize leaves is a
+// prefix of the version with newSize leaves, as described in RFC 9162. Both versions have to be
+// prefixes of the current tree and the tree has to be built with OddNodePromote, since only then the
+// roots of its older versions are reproducible.
+func (m *BaseTree) ConsistencyProof(oldSize, newSize int) ([][]byte, error) {
+	if m.config.oddNodeStrategy != OddNodePromote {
+		return nil, errors.New("error: consistency proofs require a tree built with OddNodePromote")
+	}
+
+	if oldSize <= 0 || oldSize > newSize || newSize > m.leafCount() {
+		return nil, fmt.Errorf(
+			"error: invalid tree sizes %d and %d for tree of size %d", oldSize, newSize, m.leafCount())
+	}
+
+	return m.consistencySubProof(oldSize, 0, newSize, true)
+}
+
+// consistencySubProof implements the SUBPROOF algorithm of RFC 9162 for the leaves in [lo, hi). The
+// flag complete is set while the old tree is the leftmost complete subtree of the current range.
+func (m *BaseTree) consistencySubProof(oldSize, lo, hi int, complete bool) ([][]byte, error) {
+	if oldSize == hi-lo {
+		if complete {
+			return nil, nil
+		}
+
+		hash, err := m.subtreeHash(lo, hi)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{hash}, nil
+	}
+
+	var (
+		k     = largestPowerOfTwoBelow(hi - lo)
+		proof [][]byte
+		hash  []byte
+		err   error
+	)
+
+	if oldSize <= k {
+		if proof, err = m.consistencySubProof(oldSize, lo, lo+k, complete); err != nil {
+			return nil, err
+		}
+
+		hash, err = m.subtreeHash(lo+k, hi)
+	} else {
+		if proof, err = m.consistencySubProof(oldSize-k, lo+k, hi, false); err != nil {
+			return nil, err
+		}
+
+		hash, err = m.subtreeHash(lo, lo+k)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(proof, hash), nil
+}
+
+// subtreeHash returns the root hash of the subtree holding the leaves in [lo, hi). Complete subtrees
+// are looked up in the tree, everything else is computed from them.
+func (m *BaseTree) subtreeHash(lo, hi int) ([]byte, error) {
+	size := hi - lo
+
+	if size&(size-1) == 0 && lo%size == 0 {
+		n := m.Leafs[lo]
+		for ; size > 1; size /= 2 {
+			n = n.Parent
+		}
+
+		return n.Hash, nil
+	}
+
+	k := largestPowerOfTwoBelow(size)
+
+	left, err := m.subtreeHash(lo, lo+k)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := m.subtreeHash(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.hashChildren(left, right)
+}
+
+// VerifyConsistency checks that a tree with oldSize leaves and the merkle root oldRoot is a prefix of
+// a tree with newSize leaves and the merkle root newRoot, using a proof returned by ConsistencyProof.
+// It follows the verification algorithm of RFC 9162. The options have to match the ones the tree was
+// created with. Returns true if valid and false otherwise.
+func VerifyConsistency(
+	oldRoot, newRoot []byte, oldSize, newSize int, proof [][]byte, hashFunc HashFunc, opts ...Option) (bool, error) {
+	if oldSize <= 0 || oldSize > newSize {
+		return false, fmt.Errorf("error: invalid tree sizes %d and %d", oldSize, newSize)
+	}
+
+	if oldSize == newSize {
+		return len(proof) == 0 && bytes.Equal(oldRoot, newRoot), nil
+	}
+
+	if len(proof) == 0 {
+		return false, nil
+	}
+
+	if oldSize&(oldSize-1) == 0 {
+		proof = append([][]byte{oldRoot}, proof...)
+	}
+
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	fr, sr := proof[0], proof[0]
+
+	for _, h := range proof[1:] {
+		if sn == 0 {
+			return false, nil
+		}
+
+		if fn&1 == 1 || fn == sn {
+			if fr, err = c.hashChildren(hashFunc, h, fr); err != nil {
+				return false, err
+			}
+
+			if sr, err = c.hashChildren(hashFunc, h, sr); err != nil {
+				return false, err
+			}
+
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else if sr, err = c.hashChildren(hashFunc, sr, h); err != nil {
+			return false, err
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && bytes.Equal(fr, oldRoot) && bytes.Equal(sr, newRoot), nil
+}
+
+// largestPowerOfTwoBelow returns the largest power of two smaller than n, for n > 1.
+func largestPowerOfTwoBelow(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+
+	return k
+}
@@ -0,0 +1,89 @@
+package merkletree_test
+
+import (
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestMerkleTreeConsistencyProof(t *testing.T) {
+	var payloads []merkletree.Payload
+	for _, test := range inputs {
+		payloads = append(payloads, test.payloads...)
+	}
+
+	tree, err := merkletree.NewTree(payloads, merkletree.SHA256(),
+		merkletree.WithOddNodeStrategy(merkletree.OddNodePromote))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := make([][]byte, len(payloads)+1)
+
+	for size := 1; size <= len(payloads); size++ {
+		prefix, err := merkletree.NewTree(payloads[:size], merkletree.SHA256(),
+			merkletree.WithOddNodeStrategy(merkletree.OddNodePromote))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		roots[size] = prefix.MerkleRootHash
+	}
+
+	for newSize := 1; newSize <= len(payloads); newSize++ {
+		for oldSize := 1; oldSize <= newSize; oldSize++ {
+			proof, err := tree.ConsistencyProof(oldSize, newSize)
+			if err != nil {
+				t.Fatalf("error: unexpected error for sizes %d and %d: %v", oldSize, newSize, err)
+			}
+
+			verifyConsistency(t, roots[oldSize], roots[newSize], oldSize, newSize, proof, true)
+
+			if oldSize < newSize {
+				verifyConsistency(t, roots[newSize-1], roots[newSize], oldSize, newSize, proof, oldSize == newSize-1)
+				verifyConsistency(t, roots[oldSize], roots[newSize-1], oldSize, newSize, proof, false)
+
+				tampered := append([][]byte{{123}}, proof[1:]...)
+				verifyConsistency(t, roots[oldSize], roots[newSize], oldSize, newSize, tampered, false)
+			}
+		}
+	}
+}
+
+func TestMerkleTreeConsistencyProofInvalid(t *testing.T) {
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.ConsistencyProof(1, 2); err == nil {
+		t.Error("error: expected error for tree built with OddNodeDuplicate")
+	}
+
+	tree, err = merkletree.NewTree(test.payloads, merkletree.SHA256(),
+		merkletree.WithOddNodeStrategy(merkletree.OddNodePromote))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sizes := range [][2]int{{0, 1}, {3, 2}, {1, len(test.payloads) + 1}} {
+		if _, err := tree.ConsistencyProof(sizes[0], sizes[1]); err == nil {
+			t.Errorf("error: expected error for sizes %d and %d", sizes[0], sizes[1])
+		}
+	}
+}
+
+func verifyConsistency(
+	t *testing.T, oldRoot, newRoot []byte, oldSize, newSize int, proof [][]byte, expected bool) {
+	ok, err := merkletree.VerifyConsistency(oldRoot, newRoot, oldSize, newSize, proof, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("error: expected consistency proof between sizes %d and %d to be valid: %t",
+			oldSize, newSize, expected)
+	}
+}
@@ -0,0 +1,128 @@
+package merkletree
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// CanonicalEncoder builds the canonical binary encoding of a payload, which does not depend on the
+// language or platform producing it. Fields are written in a fixed order: byte strings and strings as
+// their length as a big-endian uint32 followed by their bytes, and integers as 8 big-endian bytes, two's
+// complement for signed ones. Amounts are written as integers in minor units, never as floating point
+// numbers. Payload types implement Encoder by writing their fields to a CanonicalEncoder.
+type CanonicalEncoder struct {
+	buf []byte
+	err error
+}
+
+// WriteBytes writes the length prefixed bytes. Byte strings longer than the largest uint32 cannot be
+// written and make Bytes return an error.
+func (e *CanonicalEncoder) WriteBytes(b []byte) {
+	if uint64(len(b)) > math.MaxUint32 {
+		e.err = errors.New("error: field too long for canonical encoding")
+		return
+	}
+
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// WriteString writes the length prefixed UTF-8 bytes of the string.
+func (e *CanonicalEncoder) WriteString(s string) {
+	e.WriteBytes([]byte(s))
+}
+
+// WriteUint64 writes the integer as 8 big-endian bytes.
+func (e *CanonicalEncoder) WriteUint64(v uint64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+}
+
+// WriteInt64 writes the integer as 8 big-endian bytes in two's complement.
+func (e *CanonicalEncoder) WriteInt64(v int64) {
+	e.WriteUint64(uint64(v))
+}
+
+// Bytes returns the encoding of the fields written so far or the error of a field that could not be written.
+func (e *CanonicalEncoder) Bytes() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return e.buf, nil
+}
+
+// CanonicalDecoder reads the fields of a canonical binary encoding written by a CanonicalEncoder, in the
+// order they were written. The first field that cannot be read, because the data is truncated or a length
+// prefix exceeds the remaining data, makes every later read return zero values and Err return an error.
+type CanonicalDecoder struct {
+	data []byte
+	err  error
+}
+
+// NewCanonicalDecoder creates a CanonicalDecoder reading the given encoding.
+func NewCanonicalDecoder(data []byte) *CanonicalDecoder {
+	return &CanonicalDecoder{data: data}
+}
+
+// ReadBytes reads length prefixed bytes. The returned slice is a copy.
+func (d *CanonicalDecoder) ReadBytes() []byte {
+	length := d.next(4)
+	if length == nil {
+		return nil
+	}
+
+	b := d.next(int(binary.BigEndian.Uint32(length)))
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
+
+// ReadString reads length prefixed UTF-8 bytes as a string.
+func (d *CanonicalDecoder) ReadString() string {
+	return string(d.ReadBytes())
+}
+
+// ReadUint64 reads an integer written as 8 big-endian bytes.
+func (d *CanonicalDecoder) ReadUint64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint64(b)
+}
+
+// ReadInt64 reads an integer written as 8 big-endian bytes in two's complement.
+func (d *CanonicalDecoder) ReadInt64() int64 {
+	return int64(d.ReadUint64())
+}
+
+// Remaining returns the number of bytes not read yet.
+func (d *CanonicalDecoder) Remaining() int {
+	return len(d.data)
+}
+
+// Err returns the error of the first field that could not be read or nil if all fields were read.
+func (d *CanonicalDecoder) Err() error {
+	return d.err
+}
+
+// next consumes the next n bytes of the data. Returns nil and records an error if fewer are left.
+func (d *CanonicalDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n < 0 || n > len(d.data) {
+		d.err = errors.New("error: canonical encoding truncated")
+		return nil
+	}
+
+	b := d.data[:n:n]
+	d.data = d.data[n:]
+
+	return b
+}
@@ -0,0 +1,132 @@
+package merkletree_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// The expected values are computed independently of this package, e.g. in Python with
+// struct.pack(">I", len(field)) + field for strings, struct.pack(">q", amount) for amounts and hashlib.sha256.
+var canonicalEncodingVectors = []struct {
+	testCaseName string
+	payload      merkletree.PaymentTransactionPayload
+	encoding     string
+	hash         string
+	leafHash     string
+}{
+	{
+		testCaseName: "payment",
+		payload: merkletree.PaymentTransactionPayload{
+			SenderAddress:   "mznsVLaixRTMsdX4LuniyrMsuKqFh86yCW",
+			ReceiverAddress: "mfem2dBeAnnnLZdjyKa33mm5zzVBpUShRB",
+			Amount:          357656464,
+		},
+		encoding: "000000226d7a6e73564c61697852544d736458344c756e6979724d73754b7146683836794357" +
+			"000000226d66656d32644265416e6e6e4c5a646a794b6133336d6d357a7a56427055536852420000000015516790",
+		hash:     "fd806f45937bcb64b1d9935e4612ba84b572f86ed6f4d17af41bb209d1b5c969",
+		leafHash: "4e7545537ab0e8a031f78b789007ebf0cd0664b7b473c8f32c8834fb5c52d5e4",
+	},
+	{
+		testCaseName: "empty",
+		payload:      merkletree.PaymentTransactionPayload{},
+		encoding:     "00000000000000000000000000000000",
+		hash:         "374708fff7719dd5979ec875d56cd2286f6d3cf7ec317a3b25632aab28ec37bb",
+		leafHash:     "0a88111852095cae045340ea1f0b279944b2a756a213d9b50107d7489771e159",
+	},
+	{
+		testCaseName: "utf-8 and negative amount",
+		payload:      merkletree.PaymentTransactionPayload{SenderAddress: "a", ReceiverAddress: "Ω", Amount: -1},
+		encoding:     "000000016100000002cea9ffffffffffffffff",
+		hash:         "7159446eecb9620b35be89543369aa4f63d4cd39327614da2bb0c0ab46d4321d",
+		leafHash:     "ea7955fe1ba2a54836887df2b3da46026771e98c11b62e80669c76260be270ba",
+	},
+}
+
+func TestPaymentTransactionPayloadCanonicalEncoding(t *testing.T) {
+	for _, test := range canonicalEncodingVectors {
+		encoding, err := test.payload.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(encoding) != test.encoding {
+			t.Errorf("[test case: %s] error: expected encoding %s got %x", test.testCaseName, test.encoding, encoding)
+		}
+
+		hash, err := test.payload.CalculateHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(hash) != test.hash {
+			t.Errorf("[test case: %s] error: expected hash %s got %x", test.testCaseName, test.hash, hash)
+		}
+
+		tree, err := merkletree.NewTree([]merkletree.PaymentTransactionPayload{test.payload}, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(tree.Leafs[0].Hash) != test.leafHash {
+			t.Errorf("[test case: %s] error: expected leaf hash %s got %x",
+				test.testCaseName, test.leafHash, tree.Leafs[0].Hash)
+		}
+	}
+}
+
+func TestCanonicalEncoder(t *testing.T) {
+	var e merkletree.CanonicalEncoder
+
+	e.WriteBytes([]byte{0x01, 0x02})
+	e.WriteString("")
+	e.WriteUint64(1)
+	e.WriteInt64(-2)
+
+	encoding, err := e.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "000000020102000000000000000000000001fffffffffffffffe"
+	if hex.EncodeToString(encoding) != expected {
+		t.Errorf("error: expected encoding %s got %x", expected, encoding)
+	}
+}
+
+func TestCanonicalDecoder(t *testing.T) {
+	encoding, err := hex.DecodeString("000000020102000000000000000000000001fffffffffffffffe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := merkletree.NewCanonicalDecoder(encoding)
+
+	if b := d.ReadBytes(); hex.EncodeToString(b) != "0102" {
+		t.Errorf("error: expected bytes 0102 got %x", b)
+	}
+
+	if s := d.ReadString(); s != "" {
+		t.Errorf("error: expected empty string got %q", s)
+	}
+
+	if v := d.ReadUint64(); v != 1 {
+		t.Errorf("error: expected 1 got %d", v)
+	}
+
+	if v := d.ReadInt64(); v != -2 {
+		t.Errorf("error: expected -2 got %d", v)
+	}
+
+	if d.Err() != nil || d.Remaining() != 0 {
+		t.Errorf("error: expected all fields read got %v with %d bytes left", d.Err(), d.Remaining())
+	}
+
+	truncated := merkletree.NewCanonicalDecoder(encoding[:5])
+	truncated.ReadBytes()
+
+	if truncated.Err() == nil {
+		t.Error("error: expected error for truncated encoding")
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/powerslider/merkle-tree
 
 go 1.20
+
+require golang.org/x/crypto v0.21.0
+
+require golang.org/x/sys v0.18.0 // indirect
@@ -2,12 +2,50 @@ package merkletree
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
 	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
 )
 
+// ErrUnknownHashFunc is returned when a hash algorithm is looked up by a name that is not a known one.
+var ErrUnknownHashFunc = errors.New("error: unknown hash algorithm")
+
 // HashFunc represents a function returning the constructor of a specific type of hash algorithm.
 type HashFunc func() hash.Hash
 
+// knownHashFuncs lists the hash algorithms that can be identified by a stable name.
+var knownHashFuncs = []struct {
+	name     string
+	hashFunc HashFunc
+}{
+	{name: "sha224", hashFunc: SHA224()},
+	{name: "sha256", hashFunc: SHA256()},
+	{name: "sha384", hashFunc: SHA384()},
+	{name: "sha512", hashFunc: SHA512()},
+	{name: "sha512-256", hashFunc: SHA512_256()},
+	{name: "sha3-256", hashFunc: SHA3_256()},
+	{name: "sha3-512", hashFunc: SHA3_512()},
+	{name: "keccak256", hashFunc: Keccak256()},
+	{name: "blake2b-256", hashFunc: BLAKE2b256()},
+	{name: "sha256d", hashFunc: DoubleSHA256()},
+}
+
+// HashFuncByName returns the hash algorithm with the given stable identifier, e.g. "sha3-256", as
+// returned by Name. Returns ErrUnknownHashFunc if the name is not a known one.
+func HashFuncByName(name string) (HashFunc, error) {
+	for _, known := range knownHashFuncs {
+		if known.name == name {
+			return known.hashFunc, nil
+		}
+	}
+
+	return nil, ErrUnknownHashFunc
+}
+
 // Calculate calculates the hash of given payload using the specified hash algorithm.
 func (h HashFunc) Calculate(payload []byte) ([]byte, error) {
 	hFunc := h()
@@ -18,7 +56,117 @@ func (h HashFunc) Calculate(payload []byte) ([]byte, error) {
 	return hFunc.Sum(nil), nil
 }
 
+// knownHashProbes maps the digest of an empty input of every known hash algorithm to its name.
+var knownHashProbes = probeKnownHashFuncs()
+
+// probeKnownHashFuncs calculates the digests of an empty input of the known hash algorithms.
+func probeKnownHashFuncs() map[string]string {
+	probes := make(map[string]string, len(knownHashFuncs))
+
+	for _, known := range knownHashFuncs {
+		probe, err := known.hashFunc.Calculate(nil)
+		if err != nil {
+			panic(err)
+		}
+
+		probes[string(probe)] = known.name
+	}
+
+	return probes
+}
+
+// Name returns the stable identifier of the hash algorithm, e.g. "sha256", or an empty string if the
+// algorithm is not a known one. Algorithms are told apart by their digest of an empty input, so any
+// HashFunc wrapping a known constructor is identified as well. It calculates a digest, so trees resolve
+// the name once when they are created.
+func (h HashFunc) Name() string {
+	probe, err := h.Calculate(nil)
+	if err != nil {
+		return ""
+	}
+
+	return knownHashProbes[string(probe)]
+}
+
+// checkHashAlgorithm checks that the hash algorithm named by a proof is the given one. A proof without
+// a name does not identify its algorithm and is not checked. A named proof does not match a hash function
+// that is not a known one, since unknown algorithms cannot be told apart by name.
+func checkHashAlgorithm(proofAlgorithm string, hashFunc HashFunc) error {
+	if proofAlgorithm == "" {
+		return nil
+	}
+
+	if name := hashFunc.Name(); name != proofAlgorithm {
+		return fmt.Errorf("error: proof uses hash algorithm %q, verifying with %q", proofAlgorithm, name)
+	}
+
+	return nil
+}
+
+// sum calculates the hash of the concatenation of the given parts without modifying any of them.
+func (h HashFunc) sum(parts ...[]byte) ([]byte, error) {
+	hFunc := h()
+
+	for _, p := range parts {
+		if _, err := hFunc.Write(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return hFunc.Sum(nil), nil
+}
+
+// SHA224 returns the constructor function for the SHA-224 algorithm.
+func SHA224() HashFunc {
+	return HashFunc(sha256.New224)
+}
+
 // SHA256 returns the constructor function for the SHA-256 algorithm.
 func SHA256() HashFunc {
 	return HashFunc(sha256.New)
 }
+
+// SHA384 returns the constructor function for the SHA-384 algorithm.
+func SHA384() HashFunc {
+	return HashFunc(sha512.New384)
+}
+
+// SHA512 returns the constructor function for the SHA-512 algorithm.
+func SHA512() HashFunc {
+	return HashFunc(sha512.New)
+}
+
+// SHA512_256 returns the constructor function for the SHA-512/256 algorithm, SHA-512 truncated to
+// 256 bits with its own initial values.
+func SHA512_256() HashFunc { //nolint:revive // named after the algorithm like sha512.New512_256
+	return HashFunc(sha512.New512_256)
+}
+
+// SHA3_256 returns the constructor function for the SHA3-256 algorithm.
+func SHA3_256() HashFunc { //nolint:revive // named after the algorithm like sha3.New256
+	return HashFunc(sha3.New256)
+}
+
+// SHA3_512 returns the constructor function for the SHA3-512 algorithm.
+func SHA3_512() HashFunc { //nolint:revive // named after the algorithm like sha3.New512
+	return HashFunc(sha3.New512)
+}
+
+// Keccak256 returns the constructor function for the original Keccak-256 algorithm as used by
+// Ethereum, which differs from SHA3-256 in its padding.
+func Keccak256() HashFunc {
+	return HashFunc(sha3.NewLegacyKeccak256)
+}
+
+// BLAKE2b256 returns the constructor function for the unkeyed BLAKE2b-256 algorithm.
+func BLAKE2b256() HashFunc {
+	return func() hash.Hash {
+		h, err := blake2b.New256(nil)
+		if err != nil {
+			// Only keys longer than 64 bytes are rejected, so this cannot happen without a key.
+			panic(err)
+		}
+
+		return h
+	}
+}
@@ -0,0 +1,163 @@
+package merkletree_test
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// The expected digests of "abc" are the published test vectors of each algorithm.
+var hashFuncVectors = []struct {
+	name     string
+	hashFunc merkletree.HashFunc
+	digest   string
+}{
+	{
+		name:     "sha224",
+		hashFunc: merkletree.SHA224(),
+		digest:   "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7",
+	},
+	{
+		name:     "sha256",
+		hashFunc: merkletree.SHA256(),
+		digest:   "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+	},
+	{
+		name:     "sha384",
+		hashFunc: merkletree.SHA384(),
+		digest: "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed" +
+			"8086072ba1e7cc2358baeca134c825a7",
+	},
+	{
+		name:     "sha512",
+		hashFunc: merkletree.SHA512(),
+		digest: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a" +
+			"2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+	},
+	{
+		name:     "sha512-256",
+		hashFunc: merkletree.SHA512_256(),
+		digest:   "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23",
+	},
+	{
+		name:     "sha3-256",
+		hashFunc: merkletree.SHA3_256(),
+		digest:   "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+	},
+	{
+		name:     "sha3-512",
+		hashFunc: merkletree.SHA3_512(),
+		digest: "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e" +
+			"10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0",
+	},
+	{
+		name:     "keccak256",
+		hashFunc: merkletree.Keccak256(),
+		digest:   "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+	},
+	{
+		name:     "blake2b-256",
+		hashFunc: merkletree.BLAKE2b256(),
+		digest:   "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319",
+	},
+}
+
+func TestHashFuncs(t *testing.T) {
+	for _, test := range hashFuncVectors {
+		digest, err := test.hashFunc.Calculate([]byte("abc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(digest) != test.digest {
+			t.Errorf("[test case: %s] error: expected digest %s got %x", test.name, test.digest, digest)
+		}
+
+		if name := test.hashFunc.Name(); name != test.name {
+			t.Errorf("[test case: %s] error: expected name %s got %s", test.name, test.name, name)
+		}
+
+		hashFunc, err := merkletree.HashFuncByName(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name := hashFunc.Name(); name != test.name {
+			t.Errorf("[test case: %s] error: expected hash function named %s got %s", test.name, test.name, name)
+		}
+	}
+
+	if _, err := merkletree.HashFuncByName("md5"); !errors.Is(err, merkletree.ErrUnknownHashFunc) {
+		t.Errorf("error: expected ErrUnknownHashFunc got %v", err)
+	}
+
+	for _, hashFunc := range []merkletree.HashFunc{md5.New, sha1.New} {
+		if name := hashFunc.Name(); name != "" {
+			t.Errorf("error: expected no name for an unknown hash function got %s", name)
+		}
+	}
+}
+
+func TestVerifyProofUnidentifiedHashFunc(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.HashFunc(sha1.New))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.ProofByIndex(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proof.HashAlgorithm != "" {
+		t.Errorf("error: expected proof without hash algorithm got %s", proof.HashAlgorithm)
+	}
+
+	ok, err := merkletree.VerifyProof(tree.MerkleRootHash, inputs[0].payloads[1], *proof, sha1.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Error("error: expected proof to be valid")
+	}
+
+	// A named proof does not match an unknown hash function, even though neither of them has a name.
+	proof.HashAlgorithm = "sha256"
+
+	if _, err := merkletree.VerifyProof(tree.MerkleRootHash, inputs[0].payloads[1], *proof, sha1.New); err == nil {
+		t.Error("error: expected hash algorithm mismatch error")
+	}
+}
+
+func TestNewTreeWithHashFuncs(t *testing.T) {
+	for _, test := range hashFuncVectors {
+		tree, err := merkletree.NewTree(inputs[0].payloads, test.hashFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proof, err := tree.ProofByIndex(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if proof.HashAlgorithm != test.name {
+			t.Errorf("[test case: %s] error: expected proof hash algorithm %s got %s",
+				test.name, test.name, proof.HashAlgorithm)
+		}
+
+		ok, err := merkletree.VerifyProof(tree.MerkleRootHash, inputs[0].payloads[3], *proof, test.hashFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Errorf("[test case: %s] error: expected proof to be valid", test.name)
+		}
+	}
+}
@@ -3,50 +3,168 @@ package merkletree
 import (
 	"bytes"
 	"errors"
+	"sort"
 )
 
-// MerkleTree represents the merkle tree data structure. It holds references to the root of the tree,
-// its leaf nodes, the merkle root hash and the type of hash function it supports.
-type MerkleTree struct {
+// Tree represents the merkle tree data structure holding items of type T. It holds references to the root
+// of the tree, its leaf nodes, the merkle root hash and the type of hash function it supports. Items are
+// taken and returned as T, so they do not have to be type asserted from Payload.
+type Tree[T Payload] struct {
+	BaseTree
+}
+
+// MerkleTree is the non-generic form of Tree, holding payloads of any type.
+type MerkleTree = Tree[Payload]
+
+// BaseTree holds the part of a Tree that does not depend on the type of its items. Its fields and the
+// methods not taking items are promoted to every Tree, and the nodes of the tree refer to it.
+type BaseTree struct {
 	Root           *Node
 	Leafs          []*Node
 	MerkleRootHash []byte
 	HashFunc       HashFunc
+	config         treeConfig
+	// hashAlgorithm is the name of the hash algorithm, resolved once when the tree is created.
+	hashAlgorithm string
+	// leafIndex maps the hash of a leaf to the ascending positions of the leaves with that hash.
+	leafIndex map[string][]int
 }
 
-// NewTree creates a new MerkleTree using provided payloads and a type of hash function.
-func NewTree(pp []Payload, hashFunc HashFunc) (*MerkleTree, error) {
-	t := &MerkleTree{
-		HashFunc: hashFunc,
+// ErrLeafNotFound is returned when a leaf is looked up by a hash that no leaf of the tree has.
+var ErrLeafNotFound = errors.New("error: leaf not found in tree")
+
+// NewTree creates a new Tree using provided items, a type of hash function and optional
+// settings of the tree. Leaf and node hashes are domain separated with DefaultLeafPrefix and
+// DefaultNodePrefix unless configured otherwise.
+func NewTree[T Payload](items []T, hashFunc HashFunc, opts ...Option) (*Tree[T], error) {
+	config, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tree[T]{
+		BaseTree: BaseTree{
+			HashFunc:      hashFunc,
+			config:        config,
+			hashAlgorithm: hashFunc.Name(),
+		},
 	}
-	root, leafs, err := constructTreeFromPayloads(pp, t)
 
+	if err := t.rebuildTreeWith(payloadsOf(items)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// NewTreeFromBytes creates a new Tree holding each of the given byte slices as a BytesPayload. See NewTree.
+func NewTreeFromBytes(data [][]byte, hashFunc HashFunc, opts ...Option) (*Tree[BytesPayload], error) {
+	items := make([]BytesPayload, 0, len(data))
+
+	for _, d := range data {
+		items = append(items, d)
+	}
+
+	return NewTree(items, hashFunc, opts...)
+}
+
+// NewTreeFromHashes creates a new MerkleTree from the hashes of its leaves, e.g. calculated by another
+// system. The hashes are used as they are, like the one of a HashPayload, and no payload is stored on
+// the leaves. Proofs are built by position or hash with ProofByIndex and ProofByLeafHash and verified
+// with VerifyLeafHash, which requires the verifier to know the number of leaves of the tree. See NewTree.
+func NewTreeFromHashes(hashes [][]byte, hashFunc HashFunc, opts ...Option) (*MerkleTree, error) {
+	config, err := newTreeConfig(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	t.Root = root
-	t.Leafs = leafs
-	t.MerkleRootHash = root.Hash
+	t := &MerkleTree{
+		BaseTree: BaseTree{
+			HashFunc:      hashFunc,
+			config:        config,
+			hashAlgorithm: hashFunc.Name(),
+		},
+	}
+
+	leafNodes := make([]*Node, 0, len(hashes))
+
+	for _, h := range hashes {
+		hash, err := t.hashLeaf(HashPayload(h))
+		if err != nil {
+			return nil, err
+		}
+
+		leafNodes = append(leafNodes, &Node{
+			Hash:   hash,
+			isLeaf: true,
+			Tree:   &t.BaseTree,
+		})
+	}
+
+	if err := t.rebuildFromLeafNodes(leafNodes); err != nil {
+		return nil, err
+	}
 
 	return t, nil
 }
 
-// RebuildTree rebuilds the tree reusing only its leaf node payloads.
-func (m *MerkleTree) RebuildTree() error {
-	var pp []Payload
+// RebuildTree rebuilds the tree reusing only its leaf node payloads. The hashes of leaves without
+// a payload are reused as they are.
+func (m *BaseTree) RebuildTree() error {
+	leafNodes := make([]*Node, 0, m.leafCount())
+
+	for _, n := range m.Leafs[:m.leafCount()] {
+		leaf := &Node{
+			Hash:    n.Hash,
+			Payload: n.Payload,
+			isLeaf:  true,
+			Tree:    m,
+		}
+
+		if n.Payload != nil {
+			hash, err := m.hashLeaf(n.Payload)
+			if err != nil {
+				return err
+			}
 
-	for _, n := range m.Leafs {
-		pp = append(pp, n.Payload)
+			leaf.Hash = hash
+		}
+
+		leafNodes = append(leafNodes, leaf)
 	}
 
-	return m.RebuildTreeWith(pp)
+	return m.rebuildFromLeafNodes(leafNodes)
 }
 
-// RebuildTreeWith replaces the payloads of the tree and does a complete rebuild. No new
+// RebuildTreeWith replaces the items of the tree and does a complete rebuild. No new
 // tree instance is constructed, because the same instance is re-used.
-func (m *MerkleTree) RebuildTreeWith(pp []Payload) error {
-	root, leafs, err := constructTreeFromPayloads(pp, m)
+func (m *Tree[T]) RebuildTreeWith(items []T) error {
+	return m.rebuildTreeWith(payloadsOf(items))
+}
+
+// rebuildTreeWith replaces the payloads of the tree and does a complete rebuild.
+func (m *BaseTree) rebuildTreeWith(pp []Payload) error {
+	if len(pp) == 0 {
+		return errors.New("error: cannot construct tree with no payload")
+	}
+
+	leafNodes, err := constructLeafNodes(pp, m)
+	if err != nil {
+		return err
+	}
+
+	return m.rebuildFromLeafNodes(leafNodes)
+}
+
+// rebuildFromLeafNodes replaces the leaves of the tree with the given leaf nodes and does a complete rebuild.
+func (m *BaseTree) rebuildFromLeafNodes(leafNodes []*Node) error {
+	return m.buildFromLeafNodes(leafNodes, m.hashNodes)
+}
+
+// buildFromLeafNodes replaces the leaves of the tree with the given leaf nodes and builds the levels above
+// them, taking the hash of every node built from nodeHash.
+func (m *BaseTree) buildFromLeafNodes(leafNodes []*Node, nodeHash nodeHashFunc) error {
+	root, leafs, err := constructTreeFromLeafNodes(leafNodes, m, nodeHash)
 	if err != nil {
 		return err
 	}
@@ -54,13 +172,14 @@ func (m *MerkleTree) RebuildTreeWith(pp []Payload) error {
 	m.Root = root
 	m.Leafs = leafs
 	m.MerkleRootHash = root.Hash
+	m.leafIndex = indexLeafNodes(leafs)
 
 	return nil
 }
 
 // VerifyTree verifies the entire tree by validating the hashes at each tree level and returns true if the
 // resulting hash at the root of the tree matches the merkle root hash.
-func (m *MerkleTree) VerifyTree() (bool, error) {
+func (m *BaseTree) VerifyTree() (bool, error) {
 	calculatedMerkleRoot, err := m.Root.verifyNode()
 	if err != nil {
 		return false, err
@@ -69,97 +188,263 @@ func (m *MerkleTree) VerifyTree() (bool, error) {
 	return bytes.Equal(m.MerkleRootHash, calculatedMerkleRoot), nil
 }
 
-// VerifyPayload checks whether a given payload is part of the tree and the hashes are valid for that payload.
+// VerifyPayload checks whether a given item is part of the tree and the hashes are valid for that item.
 // Returns true if the expected merkle root is equal to the merkle root calculated from the merkle path
-// for a given payload. Returns true if valid and false otherwise.
-func (m *MerkleTree) VerifyPayload(payload Payload) (bool, error) {
-	for _, l := range m.Leafs {
-		ok, err := l.Payload.Equals(payload)
+// for a given item. Returns true if valid and false otherwise.
+func (m *Tree[T]) VerifyPayload(item T) (bool, error) {
+	index, err := m.indexOf(item)
+	if errors.Is(err, ErrPayloadNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	currentParent := m.Leafs[index].Parent
+	for currentParent != nil {
+		rightBytes, err := currentParent.Right.CalculateNodeHash()
 		if err != nil {
 			return false, err
 		}
 
-		if ok {
-			currentParent := l.Parent
-			for currentParent != nil {
-				rightBytes, err := currentParent.Right.CalculateNodeHash()
-				if err != nil {
-					return false, err
-				}
-
-				leftBytes, err := currentParent.Left.CalculateNodeHash()
-				if err != nil {
-					return false, err
-				}
-
-				hashBytes, err := m.HashFunc.Calculate(append(leftBytes, rightBytes...))
-				if err != nil {
-					return false, err
-				}
-
-				if !bytes.Equal(hashBytes, currentParent.Hash) {
-					return false, nil
-				}
-
-				currentParent = currentParent.Parent
-			}
+		leftBytes, err := currentParent.Left.CalculateNodeHash()
+		if err != nil {
+			return false, err
+		}
+
+		hashBytes, err := m.hashChildren(leftBytes, rightBytes)
+		if err != nil {
+			return false, err
+		}
+
+		if !bytes.Equal(hashBytes, currentParent.Hash) {
+			return false, nil
+		}
+
+		currentParent = currentParent.Parent
+	}
 
-			return true, nil
+	return true, nil
+}
+
+// GetMerklePath traces all the tree nodes needed for item verification.
+func (m *Tree[T]) GetMerklePath(item T) ([][]byte, []int64, error) {
+	leafIndex, err := m.indexOf(item)
+	if errors.Is(err, ErrPayloadNotFound) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := m.Leafs[leafIndex]
+	currentParent := current.Parent
+
+	var (
+		merklePath [][]byte
+		index      []int64
+	)
+
+	for currentParent != nil {
+		if bytes.Equal(currentParent.Left.Hash, current.Hash) {
+			merklePath = append(merklePath, currentParent.Right.Hash)
+			index = append(index, 1) // right leaf
+		} else {
+			merklePath = append(merklePath, currentParent.Left.Hash)
+			index = append(index, 0) // left leaf
 		}
+
+		current = currentParent
+		currentParent = currentParent.Parent
 	}
 
-	return false, nil
+	return merklePath, index, nil
 }
 
-// GetMerklePath traces all the tree nodes needed for payload verification.
-func (m *MerkleTree) GetMerklePath(payload Payload) ([][]byte, []int64, error) {
-	for _, current := range m.Leafs {
-		ok, err := current.Payload.Equals(payload)
+// IndexOf returns the position of the first leaf holding the given item. The leaf is looked up
+// by its hash in constant time. Returns ErrPayloadNotFound if the item is not part of the tree.
+func (m *Tree[T]) IndexOf(item T) (int, error) {
+	return m.indexOf(item)
+}
+
+// Leaf returns the item of the leaf at the given position. Returns an *IndexOutOfRangeError if there
+// is no leaf at the position.
+func (m *Tree[T]) Leaf(i int) (T, error) {
+	var item T
+
+	if size := m.leafCount(); i < 0 || i >= size {
+		return item, &IndexOutOfRangeError{Index: i, Size: size}
+	}
+
+	item, _ = m.Leafs[i].Payload.(T)
+
+	return item, nil
+}
+
+// Items returns the items of the leaves of the tree in order.
+func (m *Tree[T]) Items() []T {
+	items := make([]T, 0, m.leafCount())
+
+	for _, n := range m.Leafs[:m.leafCount()] {
+		item, _ := n.Payload.(T)
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// indexOf returns the position of the first leaf holding the given payload, see IndexOf.
+func (m *BaseTree) indexOf(payload Payload) (int, error) {
+	hash, err := m.hashLeaf(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, i := range m.leafIndex[string(hash)] {
+		if m.Leafs[i].hashOnly() {
+			return i, nil
+		}
+
+		ok, err := m.Leafs[i].Payload.Equals(payload)
 		if err != nil {
-			return nil, nil, err
+			return 0, err
 		}
 
 		if ok {
-			currentParent := current.Parent
-
-			var (
-				merklePath [][]byte
-				index      []int64
-			)
-
-			for currentParent != nil {
-				if bytes.Equal(currentParent.Left.Hash, current.Hash) {
-					merklePath = append(merklePath, currentParent.Right.Hash)
-					index = append(index, 1) // right leaf
-				} else {
-					merklePath = append(merklePath, currentParent.Left.Hash)
-					index = append(index, 0) // left leaf
-				}
-
-				current = currentParent
-				currentParent = currentParent.Parent
-			}
+			return i, nil
+		}
+	}
+
+	return 0, ErrPayloadNotFound
+}
+
+// LeafByHash returns the first leaf with the given hash. The leaf is looked up in constant time.
+// Returns ErrLeafNotFound if no leaf of the tree has the hash.
+func (m *BaseTree) LeafByHash(hash []byte) (*Node, error) {
+	indices := m.leafIndex[string(hash)]
+	if len(indices) == 0 {
+		return nil, ErrLeafNotFound
+	}
+
+	return m.Leafs[indices[0]], nil
+}
+
+// hashLeaf calculates the hash of a leaf holding the given payload.
+func (m *BaseTree) hashLeaf(p Payload) ([]byte, error) {
+	return m.config.hashLeaf(m.HashFunc, p)
+}
+
+// hashChildren calculates the hash of a node from the hashes of its children.
+func (m *BaseTree) hashChildren(left, right []byte) ([]byte, error) {
+	return m.config.hashChildren(m.HashFunc, left, right)
+}
+
+// nodeHashFunc returns the hash of the node built from the given children.
+type nodeHashFunc func(left, right *Node) ([]byte, error)
+
+// hashNodes calculates the hash of the node built from the given children.
+func (m *BaseTree) hashNodes(left, right *Node) ([]byte, error) {
+	return m.hashChildren(left.Hash, right.Hash)
+}
+
+// leafCount returns the number of leaves of the tree, not counting the duplicate of the last leaf
+// added to trees with an odd number of leaves.
+func (m *BaseTree) leafCount() int {
+	if n := len(m.Leafs); n > 0 && m.Leafs[n-1].isDuplicate {
+		return n - 1
+	}
+
+	return len(m.Leafs)
+}
+
+// payloadsOf returns the items as payloads.
+func payloadsOf[T Payload](items []T) []Payload {
+	pp := make([]Payload, 0, len(items))
+
+	for _, item := range items {
+		pp = append(pp, item)
+	}
+
+	return pp
+}
+
+// indexLeafNodes maps the hashes of the given leaf nodes to their positions. The duplicate of the
+// last leaf is not indexed.
+func indexLeafNodes(leafNodes []*Node) map[string][]int {
+	index := make(map[string][]int, len(leafNodes))
 
-			return merklePath, index, nil
+	for i, n := range leafNodes {
+		if n.isDuplicate {
+			continue
 		}
+
+		key := string(n.Hash)
+		index[key] = append(index[key], i)
 	}
 
-	return nil, nil, nil
+	return index
 }
 
-// constructTreeFromPayloads constructs all levels given list of payloads until it reaches
+// indexLeaf adds the leaf at the given position to the leaf index.
+func (m *BaseTree) indexLeaf(i int) {
+	key := string(m.Leafs[i].Hash)
+	indices := m.leafIndex[key]
+	pos := sort.SearchInts(indices, i)
+	indices = append(indices, 0)
+	copy(indices[pos+1:], indices[pos:])
+	indices[pos] = i
+	m.leafIndex[key] = indices
+}
+
+// unindexLeaf removes the leaf at the given position from the leaf index.
+func (m *BaseTree) unindexLeaf(i int) {
+	key := string(m.Leafs[i].Hash)
+	indices := m.leafIndex[key]
+
+	pos := sort.SearchInts(indices, i)
+	if pos == len(indices) || indices[pos] != i {
+		return
+	}
+
+	if len(indices) == 1 {
+		delete(m.leafIndex, key)
+		return
+	}
+
+	m.leafIndex[key] = append(indices[:pos], indices[pos+1:]...)
+}
+
+// constructTreeFromLeafNodes constructs all levels given list of leaf nodes until it reaches
 // the root of the tree. Returns the resulting root node and a list of the leaf nodes.
-func constructTreeFromPayloads(pp []Payload, tree *MerkleTree) (*Node, []*Node, error) {
-	if len(pp) == 0 {
-		return nil, nil, errors.New("error: cannot construct tree with no payload")
+func constructTreeFromLeafNodes(leafNodes []*Node, tree *BaseTree, nodeHash nodeHashFunc) (*Node, []*Node, error) {
+	if len(leafNodes) == 0 {
+		return nil, nil, errors.New("error: cannot construct tree with no leaf")
+	}
+
+	if err := sortLeafNodes(leafNodes, tree); err != nil {
+		return nil, nil, err
 	}
 
-	var leafNodes []*Node
+	leafNodes = duplicateLastLeafNode(leafNodes, tree)
+
+	root, err := constructNonLeafTreeLevelsFromLeafNodes(leafNodes, tree, nodeHash, 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, leafNodes, nil
+}
+
+// constructLeafNodes constructs a leaf node for each of the given payloads.
+func constructLeafNodes(pp []Payload, tree *BaseTree) ([]*Node, error) {
+	leafNodes := make([]*Node, 0, len(pp))
 
 	for _, p := range pp {
-		hash, err := p.CalculateHash()
+		hash, err := tree.hashLeaf(p)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		leafNodes = append(leafNodes, &Node{
@@ -170,9 +455,15 @@ func constructTreeFromPayloads(pp []Payload, tree *MerkleTree) (*Node, []*Node,
 		})
 	}
 
+	return leafNodes, nil
+}
+
+// duplicateLastLeafNode appends a duplicate of the last leaf node to an odd number of leaf nodes if the
+// tree pairs the last node of odd levels with a copy of itself.
+func duplicateLastLeafNode(leafNodes []*Node, tree *BaseTree) []*Node {
 	leafNodesAreOddNumber := len(leafNodes)%2 == 1
 
-	if leafNodesAreOddNumber {
+	if leafNodesAreOddNumber && tree.config.oddNodeStrategy == OddNodeDuplicate {
 		lastLeafNode := leafNodes[len(leafNodes)-1]
 
 		duplicateLeafNode := &Node{
@@ -185,51 +476,70 @@ func constructTreeFromPayloads(pp []Payload, tree *MerkleTree) (*Node, []*Node,
 		leafNodes = append(leafNodes, duplicateLeafNode)
 	}
 
-	root, err := constructNonLeafTreeLevelsFromLeafNodes(leafNodes, tree)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return root, leafNodes, nil
+	return leafNodes
 }
 
 // constructNonLeafTreeLevelsFromLeafNodes constructs the non leaf tree levels given list of leaf nodes until it
-// reaches the root of the tree. Returns the resulting root node.
-func constructNonLeafTreeLevelsFromLeafNodes(leafNodes []*Node, tree *MerkleTree) (*Node, error) {
+// reaches the root of the tree. The level is the height of the given nodes above the leaves of the tree.
+// The subtrees, indexed by their height, are complete subtrees to the left of the given nodes which are
+// reused as they are. The subtree of a level is the left neighbour of the first node built at that level.
+// The hash of every node built is taken from nodeHash. Returns the resulting root node.
+func constructNonLeafTreeLevelsFromLeafNodes(
+	leafNodes []*Node, tree *BaseTree, nodeHash nodeHashFunc, level int, subtrees []*Node) (*Node, error) {
+	if level < len(subtrees) && subtrees[level] != nil {
+		leafNodes = append([]*Node{subtrees[level]}, leafNodes...)
+	}
+
+	if len(leafNodes) == 1 && level >= len(subtrees)-1 {
+		leafNodes[0].Parent = nil
+
+		return leafNodes[0], nil
+	}
+
 	var nodes []*Node
 
 	for i := 0; i < len(leafNodes); i += 2 {
-		var (
-			left  int = i
-			right int = i + 1
-		)
+		left := leafNodes[i]
+
+		var right *Node
+
+		switch {
+		case i+1 < len(leafNodes):
+			right = leafNodes[i+1]
+		case tree.config.oddNodeStrategy == OddNodePromote:
+			nodes = append(nodes, left)
+			continue
+		case tree.config.oddNodeStrategy == OddNodeZeroPad:
+			zeroHash, err := tree.config.zeroHash(tree.HashFunc, level)
+			if err != nil {
+				return nil, err
+			}
 
-		if i+1 == len(leafNodes) {
-			right = i
+			right = &Node{
+				Hash:      zeroHash,
+				isPadding: true,
+				Tree:      tree,
+			}
+		default:
+			right = left
 		}
 
-		hashBytes, err := tree.HashFunc.Calculate(
-			append(leafNodes[left].Hash, leafNodes[right].Hash...),
-		)
+		hashBytes, err := nodeHash(left, right)
 		if err != nil {
 			return nil, err
 		}
 
 		n := &Node{
-			Left:  leafNodes[left],
-			Right: leafNodes[right],
+			Left:  left,
+			Right: right,
 			Hash:  hashBytes,
 			Tree:  tree,
 		}
 
 		nodes = append(nodes, n)
-		leafNodes[left].Parent = n
-		leafNodes[right].Parent = n
-
-		if len(leafNodes) == 2 {
-			return n, nil
-		}
+		left.Parent = n
+		right.Parent = n
 	}
 
-	return constructNonLeafTreeLevelsFromLeafNodes(nodes, tree)
+	return constructNonLeafTreeLevelsFromLeafNodes(nodes, tree, nodeHash, level+1, subtrees)
 }
@@ -3,6 +3,7 @@ package merkletree_test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"testing"
 
 	merkletree "github.com/powerslider/merkle-tree"
@@ -20,49 +21,49 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mznsVLaixRTMsdX4LuniyrMsuKqFh86yCW",
 				ReceiverAddress: "mfem2dBeAnnnLZdjyKa33mm5zzVBpUShRB",
-				Amount:          3.576564645,
+				Amount:          357656464,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mytPq1KMUWvvBwMWsVTZUvHWaLqCwa2sdQ",
 				ReceiverAddress: "mtkepLB437zfEHDx8m1YqKgtthVpkLV69d",
-				Amount:          6.4675645,
+				Amount:          646756450,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mjRNf5byc94Eh6URwV4MwpyVXcWKMq9fLp",
 				ReceiverAddress: "ms2mR5yUTgAtSt7YLJT4StpkTYvSR1NB1J",
-				Amount:          0.348,
+				Amount:          34800000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mvv25iaUDZE7JSbPTPW4jVjs7EK8mSkKHV",
 				ReceiverAddress: "n1PJCAtwaHgeeJypATLYX7ck7jB9R5CpLG",
-				Amount:          1.437,
+				Amount:          143700000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mkpH5XdvjoDJS5RN89obdkLhk2uLWFfB6e",
 				ReceiverAddress: "n2zRDstggj6DHDDwPgB6dECBJ14U7sjgAi",
-				Amount:          5.42342,
+				Amount:          542342000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "muYrJuiNmy6M7CNT2McNcgMvRN1bhLCo2z",
 				ReceiverAddress: "mt1Teo7ViAq1tMwfBq6Mnb4UhRMGQvZq62",
-				Amount:          544.4456347,
+				Amount:          54444563470,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n2m7bbFC2HcPtRpwBJdQipsCVPRd19Z1XG",
 				ReceiverAddress: "mptXBN66Qjswt4HnhfRdSfRG1dF87Uz7fp",
-				Amount:          445.42342,
+				Amount:          44542342000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "minEKZwKaieGPk57p2BRJjs7DkqFJ15U7w",
 				ReceiverAddress: "mftgohbV53HiqFNPmhez6ye1ovxJ8qrpMu",
-				Amount:          245.423,
+				Amount:          24542300000,
 			},
 		},
-		expectedHash: "7659764119c1e53ff0247692c838fe3f0f8329f57db2727bd435602dda7155cf",
+		expectedHash: "26b3f6a0ee31abfa95436c5b1ec995168e2f3afd76279c68a881376db3c7333b",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "n1DTmXp5HqwH1Qrwd4fP1BB4zpBGDsxu2V",
 			ReceiverAddress: "n4ZQsjdwNewrXXAy5C98VrYwT5MAAQ7nus",
-			Amount:          123.2303,
+			Amount:          12323030000,
 		},
 	},
 	{
@@ -71,44 +72,44 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mqTNR1FK36xqwvE6UYoPwZHg6a6Mwdciav",
 				ReceiverAddress: "mfem2dBeAnnnLZdjyKa33mm5zzVBpUShRB",
-				Amount:          7.333335,
+				Amount:          733333500,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mytPq1KMUWvvBwMWsVTZUvHWaLqCwa2sdQ",
 				ReceiverAddress: "mv9a2b3biQbaZ4nPPKvuEpF2ut1rXRswBR",
-				Amount:          6.053,
+				Amount:          605300000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mzxMjxnht5ZcCGkQwjxcM2YrjyS4wFaptu",
 				ReceiverAddress: "ms2mR5yUTgAtSt7YLJT4StpkTYvSR1NB1J",
-				Amount:          65.348,
+				Amount:          6534800000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mvv25iaUDZE7JSbPTPW4jVjs7EK8mSkKHV",
 				ReceiverAddress: "mnBtkLxzw9SKFLi8SKX16165wFiZ8Y8UYa",
-				Amount:          3.437,
+				Amount:          343700000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mfevP1TpcTZr6ZDdGzFQvVSBmdLteyzoUC",
 				ReceiverAddress: "miPgWajFJ54KYxTWTh9vFzRRRE6sfmMDDi",
-				Amount:          15.423423890,
+				Amount:          1542342389,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mr5WZMkubVjiiU2qV22CyRZ8rWrNrmF6th",
 				ReceiverAddress: "mt1Teo7ViAq1tMwfBq6Mnb4UhRMGQvZq62",
-				Amount:          17.4347,
+				Amount:          1743470000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mfevP1TpcTZr6ZDdGzFQvVSBmdLteyzoUC",
 				ReceiverAddress: "mptXBN66Qjswt4HnhfRdSfRG1dF87Uz7fp",
-				Amount:          88.42342,
+				Amount:          8842342000,
 			},
 		},
-		expectedHash: "37bbbcd13f425a9a3d6cce40570edc2baf2764e46bff15191874cb1a5035abc6",
+		expectedHash: "0406f2d88766d09383ad9a00950c575de810e3a6f6be88c2705ed56112ce4bb8",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "mz43bGoaLXwcsen97SE8LLPUGF8wu3V5g1",
 			ReceiverAddress: "mrvjMuJDts19vEqMNEJfEny3zfpy6GXY3Z",
-			Amount:          101.23048933,
+			Amount:          10123048933,
 		},
 	},
 	{
@@ -117,34 +118,34 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n3vSXc2AiUXSzofqgkajTZuvG4DzHRmUtZ",
 				ReceiverAddress: "mx88xwwNSJ7dgP6WucpnHLcKwnDvVxLBvB",
-				Amount:          0.305,
+				Amount:          30500000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mi6VZfQFgeBaVeT4SZhfZcV3qpUdJ5tVTv",
 				ReceiverAddress: "muECmHpKdDDLXiQZcdfRdC6Hc8m9A8pf3N",
-				Amount:          1.0534905,
+				Amount:          105349050,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mpYMT9z7Gj1yHP2fqtjevNVtgtMaYUmFqg",
 				ReceiverAddress: "mrqW7MuNNY6t4V2EUA9TJMXJY5Uy3582hG",
-				Amount:          54.345903,
+				Amount:          5434590300,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mjF5xMu2gxg35aWXVAa8nyjXxeBsdker73",
 				ReceiverAddress: "mt1Teo7ViAq1tMwfBq6Mnb4UhRMGQvZq62",
-				Amount:          10.4347,
+				Amount:          1043470000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mfevP1TpcTZr6ZDdGzFQvVSBmdLteyzoUC",
 				ReceiverAddress: "miPgWajFJ54KYxTWTh9vFzRRRE6sfmMDDi",
-				Amount:          15.423423890,
+				Amount:          1542342389,
 			},
 		},
-		expectedHash: "9075827b24acac27b91839fd74f73cd1e50e64db9f5ea6681aa061adde420419",
+		expectedHash: "58a22d667a0fd3b32d67dcdee84ed7eb9a38d9120eee8f24453a363cc74fb184",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "myZTJihxZrQ1NxFSvJXCxnrgKTx8zYocQz",
 			ReceiverAddress: "mmYG5VKVq4fBWekDoRVgJiPLbCiaxgNLt5",
-			Amount:          23.2304893,
+			Amount:          2323048930,
 		},
 	},
 	{
@@ -153,29 +154,29 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n3vSXc2AiUXSzofqgkajTZuvG4DzHRmUtZ",
 				ReceiverAddress: "mx88xwwNSJ7dgP6WucpnHLcKwnDvVxLBvB",
-				Amount:          0.1234,
+				Amount:          12340000,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mi6VZfQFgeBaVeT4SZhfZcV3qpUdJ5tVTv",
 				ReceiverAddress: "muECmHpKdDDLXiQZcdfRdC6Hc8m9A8pf3N",
-				Amount:          1.458495,
+				Amount:          145849500,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "moa4A5gLqSs1VsRpkiddXFuPTXkrSJ7ELP",
 				ReceiverAddress: "mrqW7MuNNY6t4V2EUA9TJMXJY5Uy3582hG",
-				Amount:          4.49503490,
+				Amount:          449503490,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "mjF5xMu2gxg35aWXVAa8nyjXxeBsdker73",
 				ReceiverAddress: "mvEZWtsX6L9xWtWUsZQSxTtoAjkdhamX3D",
-				Amount:          10.4347,
+				Amount:          1043470000,
 			},
 		},
-		expectedHash: "dcda87fa5a4f0ab2f5090223dd7150740786f3087deda58bbd4d808bfce0ea29",
+		expectedHash: "0a8e873f34c6cd8b2622fa3e6965ae4d7b9b2042220d474feabf0b609bc36666",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "mj9caWERxeG75bj5u1sAg8V1fRmeEGSU1z",
 			ReceiverAddress: "mymVUoXH7mBvFGbnikKkQ1jTU2getk3Cwg",
-			Amount:          1.5635435,
+			Amount:          156354350,
 		},
 	},
 	{
@@ -184,24 +185,24 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n3NzHnLsfKvJVL3xEkAjuKBthpjteBZmaB",
 				ReceiverAddress: "mjzDiq3fDhnqiGWV8vGvXaqi8z32zBHvvV",
-				Amount:          4.138945,
+				Amount:          413894500,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "muSUQivVvLtR88WZgrrZkg1HiZHS7475tv",
 				ReceiverAddress: "mqaQrxhzaWy4qmJMbN8qFHo4Vd3hXqrWXd",
-				Amount:          6.443595,
+				Amount:          644359500,
 			},
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n1jAt5YDf7mhdAgh6iW6tTUB2AU6TQ71in",
 				ReceiverAddress: "mvEZWtsX6L9xWtWUsZQSxTtoAjkdhamX3D",
-				Amount:          15.3485390,
+				Amount:          1534853900,
 			},
 		},
-		expectedHash: "b1aa8a0967e4f12ba8aac0b0779e17bfb554b30f1322c3b09f5ed9eec5c5cc07",
+		expectedHash: "01b07394e7924fdaeb5d965703e13577f4bcc52415a3ab80db0c6a760ca29d78",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "mrfjThNkQing9L5TzhwjsiG2qyY9d6PmPr",
 			ReceiverAddress: "mjKjgp4XLUKyBN87UiUKEQWxPgnP8ycJ7D",
-			Amount:          39.34905,
+			Amount:          3934905000,
 		},
 	},
 	{
@@ -210,21 +211,21 @@ var inputs = []struct {
 			merkletree.PaymentTransactionPayload{
 				SenderAddress:   "n3vSXc2AiUXSzofqgkajTZuvG4DzHRmUtZ",
 				ReceiverAddress: "mx88xwwNSJ7dgP6WucpnHLcKwnDvVxLBvB",
-				Amount:          0.1234,
+				Amount:          12340000,
 			},
 		},
-		expectedHash: "c16314270f5768f4845210c45e5797d3c53673952b5a780ccbc9bd31f48375cb",
+		expectedHash: "deb84e72da1785eb5e6c51559ed4fcec858340d0ab3831badd10282b20eaecac",
 		invalidPayload: merkletree.PaymentTransactionPayload{
 			SenderAddress:   "mxZJmoDfbJYYfvGAM1WKKzjowkCTZetxqZ",
 			ReceiverAddress: "mnh3nmg5dZ9YT474CaUKixovzK6K3YRrRD",
-			Amount:          3.53453,
+			Amount:          353453000,
 		},
 	},
 }
 
 func TestNewTree(t *testing.T) {
 	for _, test := range inputs {
-		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithLegacyHashing())
 		if err != nil {
 			t.Errorf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
 		}
@@ -246,7 +247,7 @@ func TestNewTree(t *testing.T) {
 
 func TestMerkleTreeVerifyTree(t *testing.T) {
 	for _, test := range inputs {
-		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithLegacyHashing())
 		if err != nil {
 			t.Errorf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
 		}
@@ -276,7 +277,7 @@ func TestMerkleTreeVerifyTree(t *testing.T) {
 
 func TestMerkleTreeVerifyPayload(t *testing.T) {
 	for _, test := range inputs {
-		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithLegacyHashing())
 		if err != nil {
 			t.Errorf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
 		}
@@ -303,7 +304,7 @@ func TestMerkleTreeVerifyPayload(t *testing.T) {
 
 func TestMerkleTreeGetMerklePath(t *testing.T) {
 	for _, test := range inputs {
-		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithLegacyHashing())
 		if err != nil {
 			t.Errorf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
 		}
@@ -382,3 +383,175 @@ func verifyInvalidPayload(
 		t.Errorf("[test case: %s] error: expected invalid content", testCaseName)
 	}
 }
+
+func TestMerkleTreeIndexOf(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, payload := range test.payloads {
+			index, err := tree.IndexOf(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if index != i {
+				t.Errorf("[test case: %s] error: expected index %d got %d", test.testCaseName, i, index)
+			}
+		}
+
+		if _, err := tree.IndexOf(test.invalidPayload); !errors.Is(err, merkletree.ErrPayloadNotFound) {
+			t.Errorf("[test case: %s] error: expected ErrPayloadNotFound got %v", test.testCaseName, err)
+		}
+
+		reversed := make([]merkletree.Payload, len(test.payloads))
+		for i, payload := range test.payloads {
+			reversed[len(reversed)-1-i] = payload
+		}
+
+		if err := tree.RebuildTreeWith(reversed); err != nil {
+			t.Fatal(err)
+		}
+
+		index, err := tree.IndexOf(test.payloads[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index != len(reversed)-1 {
+			t.Errorf("[test case: %s] error: expected index %d after rebuild got %d",
+				test.testCaseName, len(reversed)-1, index)
+		}
+	}
+}
+
+func TestMerkleTreeIndexOfDuplicatePayloads(t *testing.T) {
+	payloads := inputs[0].payloads
+	duplicated := []merkletree.Payload{payloads[0], payloads[1], payloads[0], payloads[1], payloads[1]}
+
+	tree, err := merkletree.NewTree(duplicated, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, payload := range payloads[:2] {
+		index, err := tree.IndexOf(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index != i {
+			t.Errorf("error: expected first index %d got %d", i, index)
+		}
+	}
+}
+
+func TestMerkleTreeLeafByHash(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := range test.payloads {
+			leaf, err := tree.LeafByHash(tree.Leafs[i].Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if leaf != tree.Leafs[i] {
+				t.Errorf("[test case: %s] error: expected leaf %d", test.testCaseName, i)
+			}
+		}
+
+		if _, err := tree.LeafByHash(tree.Root.Hash); !errors.Is(err, merkletree.ErrLeafNotFound) {
+			t.Errorf("[test case: %s] error: expected ErrLeafNotFound got %v", test.testCaseName, err)
+		}
+	}
+}
+
+func TestNewTreeFromHashes(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var hashes [][]byte
+
+			for _, n := range tree.Leafs[:len(test.payloads)] {
+				hashes = append(hashes, n.Hash)
+			}
+
+			hashTree, err := merkletree.NewTreeFromHashes(hashes, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(hashTree.MerkleRootHash, tree.MerkleRootHash) {
+				t.Errorf("[test case: %s] error: expected hash equal to %x got %x",
+					test.testCaseName, tree.MerkleRootHash, hashTree.MerkleRootHash)
+			}
+
+			for i, h := range hashes {
+				if hashTree.Leafs[i].Payload != nil {
+					t.Errorf("[test case: %s] error: expected leaf %d without payload", test.testCaseName, i)
+				}
+
+				proof, err := hashTree.ProofByIndex(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ok, err := merkletree.VerifyLeafHash(
+					hashTree.MerkleRootHash, h, len(hashes), *proof, merkletree.SHA256(), opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !ok {
+					t.Errorf("[test case: %s] error: expected proof of leaf hash %d to be valid", test.testCaseName, i)
+				}
+
+				verifyProof(t, test.testCaseName, hashTree.MerkleRootHash, test.payloads[i], *proof, true, opt)
+
+				index, err := hashTree.IndexOf(merkletree.HashPayload(h))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(hashTree.Leafs[index].Hash, h) {
+					t.Errorf("[test case: %s] error: expected leaf with hash %x got %x",
+						test.testCaseName, h, hashTree.Leafs[index].Hash)
+				}
+			}
+
+			if err := hashTree.RebuildTree(); err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err := hashTree.VerifyTree()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok || !bytes.Equal(hashTree.MerkleRootHash, tree.MerkleRootHash) {
+				t.Errorf("[test case: %s] error: expected rebuilt tree with hash %x to be valid got %x",
+					test.testCaseName, tree.MerkleRootHash, hashTree.MerkleRootHash)
+			}
+		}
+	}
+
+	if _, err := merkletree.NewTreeFromHashes([][]byte{{0x01}}, merkletree.SHA256()); err == nil {
+		t.Error("error: expected error for leaf hash of the wrong size")
+	}
+
+	if _, err := merkletree.NewTreeFromHashes(nil, merkletree.SHA256()); err == nil {
+		t.Error("error: expected error for no leaf hashes")
+	}
+}
@@ -0,0 +1,334 @@
+package merkletree
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrHistoryPruned is returned when a proof or root of a MountainRange needs nodes that are not kept.
+var ErrHistoryPruned = errors.New("error: history of mountain range not kept")
+
+// MountainRange represents a Merkle Mountain Range, an append-only accumulator made of the perfect binary
+// trees, or mountains, the leaves can be grouped into from left to right. There is one mountain for each
+// bit set in the number of leaves and appending a leaf merges at most log n of them. The merkle root is
+// the hash of the peaks of the mountains bagged from right to left, which makes it identical to the root
+// of a MerkleTree with the same leaves using OddNodePromote.
+//
+// Only the peaks are kept unless the range is created with WithHistory, in which case the nodes below them
+// are kept too and proofs can be built against the range at any of its earlier sizes. Prune drops the part
+// of the history no longer needed.
+type MountainRange struct {
+	MerkleRootHash []byte
+	HashFunc       HashFunc
+	config         treeConfig
+	// hashAlgorithm is the name of the hash algorithm, resolved once when the range is created.
+	hashAlgorithm string
+	// size is the number of leaves appended to the range.
+	size int
+	// peaks are the roots of the mountains from left to right, which is from the highest to the lowest.
+	peaks []*Node
+	// prunedBefore is the position of the first leaf whose history is kept.
+	prunedBefore int
+}
+
+// mountain is the position of a perfect binary tree in a MountainRange, given by the position of its first
+// leaf and its height.
+type mountain struct {
+	start  int
+	height int
+}
+
+// NewMountainRange creates an empty MountainRange using the given type of hash function. Of the options,
+// only WithHistory and the ones configuring the hashing of leaves and nodes apply. The same hashing options
+// have to be passed to VerifyMountainRangeProof.
+func NewMountainRange(hashFunc HashFunc, opts ...Option) (*MountainRange, error) {
+	config, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MountainRange{
+		HashFunc:      hashFunc,
+		config:        config,
+		hashAlgorithm: hashFunc.Name(),
+	}, nil
+}
+
+// Size returns the number of leaves appended to the range.
+func (r *MountainRange) Size() int {
+	return r.size
+}
+
+// Append adds the payloads as new leaves at the end of the range. Each leaf merges the mountains of equal
+// height at the end of the range, so only O(log n) nodes are created for it. The peaks are bagged into the
+// new merkle root once all payloads are added.
+func (r *MountainRange) Append(pp ...Payload) error {
+	if len(pp) == 0 {
+		return nil
+	}
+
+	leafNodes := make([]*Node, 0, len(pp))
+
+	for _, p := range pp {
+		hash, err := r.config.hashLeaf(r.HashFunc, p)
+		if err != nil {
+			return err
+		}
+
+		leafNodes = append(leafNodes, &Node{Hash: hash, Payload: p, isLeaf: true})
+	}
+
+	peaks, size := r.peaks, r.size
+
+	for _, n := range leafNodes {
+		for merges := size; merges&1 == 1; merges >>= 1 {
+			left := peaks[len(peaks)-1]
+
+			hash, err := r.config.hashChildren(r.HashFunc, left.Hash, n.Hash)
+			if err != nil {
+				return err
+			}
+
+			parent := &Node{Hash: hash}
+
+			if r.config.history {
+				parent.Left, parent.Right = left, n
+				left.Parent, n.Parent = parent, parent
+			}
+
+			peaks = peaks[:len(peaks)-1]
+			n = parent
+		}
+
+		peaks = append(peaks, n)
+		size++
+	}
+
+	root, err := r.bagPeaks(peaks)
+	if err != nil {
+		return err
+	}
+
+	r.peaks, r.size, r.MerkleRootHash = peaks, size, root
+
+	return nil
+}
+
+// RootAt returns the merkle root of the range at the time it had the given number of leaves. Returns an
+// *IndexOutOfRangeError if the range never had the size and ErrHistoryPruned if the peaks at the size
+// are not kept.
+func (r *MountainRange) RootAt(size int) ([]byte, error) {
+	if size < 1 || size > r.size {
+		return nil, &IndexOutOfRangeError{Index: size, Size: r.size}
+	}
+
+	if size == r.size {
+		return r.MerkleRootHash, nil
+	}
+
+	peaks, err := r.peaksAt(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.bagPeaks(peaks)
+}
+
+// ProofByIndex builds a Proof for the leaf at the given position against the current merkle root. See
+// ProofByIndexAt.
+func (r *MountainRange) ProofByIndex(i int) (*Proof, error) {
+	return r.ProofByIndexAt(i, r.size)
+}
+
+// ProofByIndexAt builds a Proof for the leaf at the given position against the merkle root of the range
+// at the time it had the given number of leaves. Returns an *IndexOutOfRangeError if there was no leaf at
+// the position and ErrHistoryPruned if the nodes on the path of the leaf are not kept.
+func (r *MountainRange) ProofByIndexAt(i, size int) (*Proof, error) {
+	if size < 1 || size > r.size {
+		return nil, &IndexOutOfRangeError{Index: size, Size: r.size}
+	}
+
+	if i < 0 || i >= size {
+		return nil, &IndexOutOfRangeError{Index: i, Size: size}
+	}
+
+	if i < r.prunedBefore {
+		return nil, ErrHistoryPruned
+	}
+
+	peaks, err := r.peaksAt(size)
+	if err != nil {
+		return nil, err
+	}
+
+	mountains := mountainsOf(size)
+
+	j := 0
+	for i >= mountains[j].start+1<<mountains[j].height {
+		j++
+	}
+
+	var (
+		siblings   [][]byte
+		directions []int64
+	)
+
+	n := peaks[j]
+
+	for height := mountains[j].height; height > 0; height-- {
+		if n.Left == nil {
+			return nil, ErrHistoryPruned
+		}
+
+		if (i-mountains[j].start)>>(height-1)&1 == 0 {
+			siblings = append([][]byte{n.Right.Hash}, siblings...)
+			directions = append([]int64{1}, directions...) // right leaf
+			n = n.Left
+		} else {
+			siblings = append([][]byte{n.Left.Hash}, siblings...)
+			directions = append([]int64{0}, directions...) // left leaf
+			n = n.Right
+		}
+	}
+
+	if j < len(peaks)-1 {
+		bagged, err := r.bagPeaks(peaks[j+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		siblings = append(siblings, bagged)
+		directions = append(directions, 1) // right leaf
+	}
+
+	for k := j - 1; k >= 0; k-- {
+		siblings = append(siblings, peaks[k].Hash)
+		directions = append(directions, 0) // left leaf
+	}
+
+	return &Proof{
+		LeafHash:      n.Hash,
+		Siblings:      siblings,
+		Directions:    directions,
+		LeafIndex:     i,
+		TreeSize:      size,
+		HashAlgorithm: r.hashAlgorithm,
+	}, nil
+}
+
+// Prune drops the history of the leaves before the given position. Proofs can still be built for the later
+// leaves against any size of the range larger than the position. It has no effect on a range created without
+// WithHistory. Returns an *IndexOutOfRangeError if the position is larger than the size of the range.
+func (r *MountainRange) Prune(before int) error {
+	if before < 0 || before > r.size {
+		return &IndexOutOfRangeError{Index: before, Size: r.size}
+	}
+
+	if before <= r.prunedBefore {
+		return nil
+	}
+
+	for j, m := range mountainsOf(r.size) {
+		pruneMountain(r.peaks[j], m.start, m.height, before)
+	}
+
+	r.prunedBefore = before
+
+	return nil
+}
+
+// peaksAt returns the peaks of the range at the time it had the given number of leaves. The mountains of
+// an earlier size are subtrees of the current mountains. Returns ErrHistoryPruned if they are not kept.
+func (r *MountainRange) peaksAt(size int) ([]*Node, error) {
+	if size == r.size {
+		return r.peaks, nil
+	}
+
+	current := mountainsOf(r.size)
+	mountains := mountainsOf(size)
+	peaks := make([]*Node, 0, len(mountains))
+
+	j := 0
+
+	for _, m := range mountains {
+		for m.start >= current[j].start+1<<current[j].height {
+			j++
+		}
+
+		n := r.peaks[j]
+
+		for height := current[j].height; height > m.height; height-- {
+			if n.Left == nil {
+				return nil, ErrHistoryPruned
+			}
+
+			if (m.start-current[j].start)>>(height-1)&1 == 0 {
+				n = n.Left
+			} else {
+				n = n.Right
+			}
+		}
+
+		peaks = append(peaks, n)
+	}
+
+	return peaks, nil
+}
+
+// bagPeaks hashes the peaks into a single merkle root, from right to left.
+func (r *MountainRange) bagPeaks(peaks []*Node) ([]byte, error) {
+	root := peaks[len(peaks)-1].Hash
+
+	for k := len(peaks) - 2; k >= 0; k-- {
+		var err error
+
+		if root, err = r.config.hashChildren(r.HashFunc, peaks[k].Hash, root); err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// VerifyMountainRangeProof checks that the payload is part of a MountainRange with the given merkle root by
+// recomputing the root from the payload and the proof alone. As the root of a MountainRange is the root of
+// a MerkleTree using OddNodePromote, this is VerifyProof for such a tree. The hashing options have to match
+// the ones the range was created with. Returns true if valid and false otherwise.
+func VerifyMountainRangeProof(
+	root []byte, payload Payload, proof Proof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	opts = append(append([]Option{}, opts...), WithOddNodeStrategy(OddNodePromote))
+
+	return VerifyProof(root, payload, proof, hashFunc, opts...)
+}
+
+// mountainsOf returns the mountains of a range with size leaves from left to right.
+func mountainsOf(size int) []mountain {
+	var mountains []mountain
+
+	start := 0
+
+	for height := bits.Len(uint(size)) - 1; height >= 0; height-- {
+		if size>>height&1 == 1 {
+			mountains = append(mountains, mountain{start: start, height: height})
+			start += 1 << height
+		}
+	}
+
+	return mountains
+}
+
+// pruneMountain drops the children of the nodes of the mountain with the given first leaf and height that
+// only cover leaves before the given position.
+func pruneMountain(n *Node, start, height, before int) {
+	if n == nil || n.Left == nil {
+		return
+	}
+
+	if start+1<<height <= before {
+		n.Left, n.Right = nil, nil
+		return
+	}
+
+	pruneMountain(n.Left, start, height-1, before)
+	pruneMountain(n.Right, start+1<<(height-1), height-1, before)
+}
@@ -0,0 +1,210 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func mountainRangePayloads(n int) []merkletree.Payload {
+	pp := make([]merkletree.Payload, 0, n)
+
+	for i := 0; i < n; i++ {
+		pp = append(pp, merkletree.PaymentTransactionPayload{
+			SenderAddress:   fmt.Sprintf("sender-%d", i),
+			ReceiverAddress: fmt.Sprintf("receiver-%d", i),
+			Amount:          int64(i),
+		})
+	}
+
+	return pp
+}
+
+func newMountainRange(t *testing.T, pp []merkletree.Payload, opts ...merkletree.Option) *merkletree.MountainRange {
+	t.Helper()
+
+	r, err := merkletree.NewMountainRange(merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range pp {
+		if err := r.Append(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return r
+}
+
+func TestMountainRangeRoot(t *testing.T) {
+	pp := mountainRangePayloads(20)
+
+	for _, opts := range [][]merkletree.Option{nil, {merkletree.WithHistory()}, {merkletree.WithLegacyHashing()}} {
+		r, err := merkletree.NewMountainRange(merkletree.SHA256(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for size := 1; size <= len(pp); size++ {
+			if err := r.Append(pp[size-1]); err != nil {
+				t.Fatal(err)
+			}
+
+			tree, err := merkletree.NewTree(pp[:size], merkletree.SHA256(),
+				append(opts, merkletree.WithOddNodeStrategy(merkletree.OddNodePromote))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Size() != size {
+				t.Errorf("error: expected size %d got %d", size, r.Size())
+			}
+
+			if !bytes.Equal(r.MerkleRootHash, tree.MerkleRootHash) {
+				t.Errorf("[size: %d] error: expected root %x got %x", size, tree.MerkleRootHash, r.MerkleRootHash)
+			}
+		}
+	}
+}
+
+func TestMountainRangeAppendBatch(t *testing.T) {
+	pp := mountainRangePayloads(13)
+	expected := newMountainRange(t, pp)
+
+	r := newMountainRange(t, pp[:5])
+	if err := r.Append(pp[5:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(r.MerkleRootHash, expected.MerkleRootHash) {
+		t.Errorf("error: expected root %x got %x", expected.MerkleRootHash, r.MerkleRootHash)
+	}
+}
+
+func TestMountainRangeProofAtHistoricalSizes(t *testing.T) {
+	pp := mountainRangePayloads(20)
+	r := newMountainRange(t, pp, merkletree.WithHistory())
+
+	for size := 1; size <= len(pp); size++ {
+		tree, err := merkletree.NewTree(pp[:size], merkletree.SHA256(),
+			merkletree.WithOddNodeStrategy(merkletree.OddNodePromote))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		root, err := r.RootAt(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(root, tree.MerkleRootHash) {
+			t.Errorf("[size: %d] error: expected root %x got %x", size, tree.MerkleRootHash, root)
+		}
+
+		for i := 0; i < size; i++ {
+			proof, err := r.ProofByIndexAt(i, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := tree.ProofByIndex(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertHashes(t, proof.Siblings, hexHashes(expected.Siblings))
+			verifyMountainRangeProof(t, root, pp[i], proof, true)
+			verifyMountainRangeProof(t, root, pp[(i+1)%len(pp)], proof, false)
+		}
+	}
+}
+
+func TestMountainRangeWithoutHistory(t *testing.T) {
+	pp := mountainRangePayloads(7)
+	r := newMountainRange(t, pp)
+
+	if _, err := r.ProofByIndex(0); !errors.Is(err, merkletree.ErrHistoryPruned) {
+		t.Errorf("error: expected ErrHistoryPruned got %v", err)
+	}
+
+	if _, err := r.RootAt(5); !errors.Is(err, merkletree.ErrHistoryPruned) {
+		t.Errorf("error: expected ErrHistoryPruned got %v", err)
+	}
+
+	proof, err := r.ProofByIndex(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyMountainRangeProof(t, r.MerkleRootHash, pp[6], proof, true)
+}
+
+func TestMountainRangePrune(t *testing.T) {
+	pp := mountainRangePayloads(20)
+	r := newMountainRange(t, pp[:11], merkletree.WithHistory())
+
+	if err := r.Prune(6); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Append(pp[11:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	for size := 7; size <= len(pp); size++ {
+		root, err := r.RootAt(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < size; i++ {
+			proof, err := r.ProofByIndexAt(i, size)
+			if i < 6 {
+				if !errors.Is(err, merkletree.ErrHistoryPruned) {
+					t.Errorf("[size: %d] error: expected ErrHistoryPruned for leaf %d got %v", size, i, err)
+				}
+
+				continue
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyMountainRangeProof(t, root, pp[i], proof, true)
+		}
+	}
+
+	if err := r.Prune(len(pp) + 1); err == nil {
+		t.Error("error: expected error when pruning beyond the size of the range")
+	}
+}
+
+func hexHashes(hashes [][]byte) []string {
+	hexes := make([]string, 0, len(hashes))
+
+	for _, h := range hashes {
+		hexes = append(hexes, hex.EncodeToString(h))
+	}
+
+	return hexes
+}
+
+func verifyMountainRangeProof(t *testing.T, root []byte, payload merkletree.Payload, proof *merkletree.Proof,
+	expected bool) {
+	t.Helper()
+
+	ok, err := merkletree.VerifyMountainRangeProof(root, payload, *proof, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("error: expected proof of leaf %d at size %d to be %t", proof.LeafIndex, proof.TreeSize, expected)
+	}
+}
@@ -0,0 +1,249 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+// MultiProof is a single inclusion proof for several leaves of the same tree. Sibling hashes shared by
+// the paths of the proven leaves, or computable from the proven leaves themselves, are included only once.
+type MultiProof struct {
+	// LeafIndices are the positions of the proven leaves in the order their payloads were requested.
+	LeafIndices []int
+	// Hashes are the sibling hashes that cannot be computed from the proven leaves, in the order
+	// they are consumed while rebuilding the root.
+	Hashes [][]byte
+	// Flags tells for each node combined while rebuilding the root, level by level from left to right,
+	// whether its sibling is computed from the proven leaves or is padding (true) or is taken from
+	// Hashes (false).
+	Flags []bool
+	// TreeSize is the number of leaves in the tree the proof was generated from.
+	TreeSize int
+	// HashAlgorithm is the name of the hash algorithm of the tree or empty if it is not a known one.
+	HashAlgorithm string
+}
+
+// multiProofEntry is a node on the paths of the proven leaves together with its position in its level.
+type multiProofEntry struct {
+	index int
+	hash  []byte
+	node  *Node
+}
+
+// GetMultiProof builds a MultiProof for the given items. Returns ErrPayloadNotFound if any of
+// the items is not part of the tree.
+func (m *Tree[T]) GetMultiProof(items []T) (*MultiProof, error) {
+	if len(items) == 0 {
+		return nil, errors.New("error: cannot construct multiproof with no payload")
+	}
+
+	proof := &MultiProof{
+		TreeSize:      m.leafCount(),
+		HashAlgorithm: m.hashAlgorithm,
+	}
+
+	for _, item := range items {
+		index, err := m.indexOf(item)
+		if err != nil {
+			return nil, err
+		}
+
+		proof.LeafIndices = append(proof.LeafIndices, index)
+	}
+
+	entries, _, err := sortedMultiProofEntries(proof.LeafIndices, func(i int) (*Node, []byte, error) {
+		return m.Leafs[proof.LeafIndices[i]], m.Leafs[proof.LeafIndices[i]].Hash, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	height := m.config.treeHeight(proof.TreeSize)
+
+	for level, width := 0, proof.TreeSize; level < height; level, width = level+1, (width+1)/2 {
+		var parents []multiProofEntry
+
+		for i := 0; i < len(entries); i++ {
+			current := entries[i]
+			parent := current.node.Parent
+			unpaired := current.index%2 == 0 && current.index == width-1
+
+			switch {
+			case unpaired && m.config.oddNodeStrategy == OddNodePromote:
+				parents = append(parents, multiProofEntry{index: current.index / 2, node: current.node})
+				continue
+			case unpaired:
+				proof.Flags = append(proof.Flags, true)
+			case current.index%2 == 0 && i+1 < len(entries) && entries[i+1].index == current.index+1:
+				proof.Flags = append(proof.Flags, true)
+				i++
+			case current.index%2 == 0:
+				proof.Flags = append(proof.Flags, false)
+				proof.Hashes = append(proof.Hashes, parent.Right.Hash)
+			default:
+				proof.Flags = append(proof.Flags, false)
+				proof.Hashes = append(proof.Hashes, parent.Left.Hash)
+			}
+
+			parents = append(parents, multiProofEntry{index: current.index / 2, hash: parent.Hash, node: parent})
+		}
+
+		entries = parents
+	}
+
+	return proof, nil
+}
+
+// VerifyMultiProof checks that all the payloads are part of a tree with the given merkle root by
+// rebuilding the root from the payloads and the multiproof alone. The payloads must be given in the
+// same order as the leaf indices of the proof. The options have to match the ones the tree was created
+// with. Returns true if valid and false otherwise. Returns ErrLeafHashPayload for a HashPayload.
+func VerifyMultiProof[T Payload](
+	root []byte, payloads []T, proof MultiProof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	if err := checkHashAlgorithm(proof.HashAlgorithm, hashFunc); err != nil {
+		return false, err
+	}
+
+	if len(payloads) == 0 || len(payloads) != len(proof.LeafIndices) {
+		return false, nil
+	}
+
+	for _, index := range proof.LeafIndices {
+		if index < 0 || index >= proof.TreeSize {
+			return false, nil
+		}
+	}
+
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	entries, ok, err := sortedMultiProofEntries(proof.LeafIndices, func(i int) (*Node, []byte, error) {
+		if err := rejectHashPayload(payloads[i]); err != nil {
+			return nil, nil, err
+		}
+
+		hash, err := c.hashLeaf(hashFunc, payloads[i])
+
+		return nil, hash, err
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+
+	var (
+		height                = c.treeHeight(proof.TreeSize)
+		flagsUsed, hashesUsed int
+	)
+
+	for level, width := 0, proof.TreeSize; level < height; level, width = level+1, (width+1)/2 {
+		var parents []multiProofEntry
+
+		zeroHash, err := c.zeroHash(hashFunc, level)
+		if err != nil {
+			return false, err
+		}
+
+		for i := 0; i < len(entries); i++ {
+			current := entries[i]
+			unpaired := current.index%2 == 0 && current.index == width-1
+
+			if unpaired && c.oddNodeStrategy == OddNodePromote {
+				parents = append(parents, multiProofEntry{index: current.index / 2, hash: current.hash})
+				continue
+			}
+
+			if flagsUsed == len(proof.Flags) {
+				return false, nil
+			}
+
+			flag := proof.Flags[flagsUsed]
+			flagsUsed++
+
+			var left, right []byte
+
+			siblingComputed := true
+
+			switch {
+			case unpaired && c.oddNodeStrategy == OddNodeZeroPad:
+				left, right = current.hash, zeroHash
+			case unpaired:
+				left, right = current.hash, current.hash
+			case current.index%2 == 0 && i+1 < len(entries) && entries[i+1].index == current.index+1:
+				left, right = current.hash, entries[i+1].hash
+				i++
+			default:
+				if hashesUsed == len(proof.Hashes) {
+					return false, nil
+				}
+
+				siblingComputed = false
+				left, right = current.hash, proof.Hashes[hashesUsed]
+				hashesUsed++
+
+				if current.index%2 == 1 {
+					left, right = right, left
+				}
+			}
+
+			if flag != siblingComputed {
+				return false, nil
+			}
+
+			hash, err := c.hashChildren(hashFunc, left, right)
+			if err != nil {
+				return false, err
+			}
+
+			parents = append(parents, multiProofEntry{index: current.index / 2, hash: hash})
+		}
+
+		entries = parents
+	}
+
+	if flagsUsed != len(proof.Flags) || hashesUsed != len(proof.Hashes) {
+		return false, nil
+	}
+
+	return bytes.Equal(entries[0].hash, root), nil
+}
+
+// sortedMultiProofEntries creates the entries of the proven leaves ordered by their position in the tree.
+// The entry of the i-th leaf index is resolved through the given function. Leaves requested more than
+// once are kept once. Returns false if the same leaf is requested with different hashes.
+func sortedMultiProofEntries(
+	indices []int, resolve func(i int) (*Node, []byte, error)) ([]multiProofEntry, bool, error) {
+	entries := make([]multiProofEntry, 0, len(indices))
+
+	for i, index := range indices {
+		node, hash, err := resolve(i)
+		if err != nil {
+			return nil, false, err
+		}
+
+		entries = append(entries, multiProofEntry{index: index, hash: hash, node: node})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].index < entries[j].index
+	})
+
+	unique := entries[:1]
+
+	for _, e := range entries[1:] {
+		last := unique[len(unique)-1]
+
+		if e.index != last.index {
+			unique = append(unique, e)
+			continue
+		}
+
+		if !bytes.Equal(e.hash, last.hash) {
+			return nil, false, nil
+		}
+	}
+
+	return unique, true, nil
+}
@@ -0,0 +1,114 @@
+package merkletree_test
+
+import (
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestMerkleTreeGetMultiProof(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		testMerkleTreeGetMultiProof(t, merkletree.WithOddNodeStrategy(strategy))
+	}
+}
+
+func testMerkleTreeGetMultiProof(t *testing.T, opt merkletree.Option) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+		if err != nil {
+			t.Fatalf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
+		}
+
+		last := len(test.payloads) - 1
+		selections := [][]merkletree.Payload{
+			test.payloads,
+			{test.payloads[0]},
+			{test.payloads[last]},
+			{test.payloads[last], test.payloads[0]},
+			{test.payloads[0], test.payloads[last], test.payloads[0]},
+		}
+
+		for _, selection := range selections {
+			proof, err := tree.GetMultiProof(selection)
+			if err != nil {
+				t.Fatalf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
+			}
+
+			verifyMultiProof(t, test.testCaseName, tree.MerkleRootHash, selection, *proof, true, opt)
+			verifyMultiProof(t, test.testCaseName, []byte{123}, selection, *proof, false, opt)
+
+			invalid := append([]merkletree.Payload{test.invalidPayload}, selection[1:]...)
+			verifyMultiProof(t, test.testCaseName, tree.MerkleRootHash, invalid, *proof, false, opt)
+
+			if len(proof.Hashes) > 0 {
+				tampered := *proof
+				tampered.Hashes = append([][]byte{{123}}, proof.Hashes[1:]...)
+				verifyMultiProof(t, test.testCaseName, tree.MerkleRootHash, selection, tampered, false, opt)
+			}
+		}
+
+		proof, err := tree.GetMultiProof(test.payloads)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(proof.Hashes) != 0 {
+			t.Errorf("[test case: %s] error: expected no sibling hashes when proving all leaves got %d",
+				test.testCaseName, len(proof.Hashes))
+		}
+
+		if _, err := tree.GetMultiProof([]merkletree.Payload{test.invalidPayload}); err == nil {
+			t.Errorf("[test case: %s] error: expected error for payload not in tree", test.testCaseName)
+		}
+	}
+}
+
+func TestMultiProofDeduplicatesSiblings(t *testing.T) {
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.GetMultiProof(test.payloads[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The two leaves are siblings, so only the two subtrees to their right are needed.
+	if len(proof.Hashes) != 2 {
+		t.Errorf("error: expected 2 sibling hashes got %d", len(proof.Hashes))
+	}
+}
+
+func TestVerifyMultiProofConflictingPayloads(t *testing.T) {
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.GetMultiProof([]merkletree.Payload{test.payloads[0], test.payloads[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := []merkletree.Payload{test.payloads[0], test.invalidPayload}
+	verifyMultiProof(t, test.testCaseName, tree.MerkleRootHash, payloads, *proof, false)
+}
+
+func verifyMultiProof(
+	t *testing.T, testCaseName string, root []byte, payloads []merkletree.Payload, proof merkletree.MultiProof,
+	expected bool, opts ...merkletree.Option) {
+	ok, err := merkletree.VerifyMultiProof(root, payloads, proof, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("[test case: %s] error: expected multiproof of leaves %v to be valid: %t",
+			testCaseName, proof.LeafIndices, expected)
+	}
+}
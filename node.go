@@ -5,12 +5,13 @@ import "fmt"
 // Node represents a node, root, or leaf in the tree. It stores pointers to its immediate
 // relationships, a hash, the content stored if it is a leaf, and other metadata.
 type Node struct {
-	Tree        *MerkleTree
+	Tree        *BaseTree
 	Parent      *Node
 	Left        *Node
 	Right       *Node
 	isLeaf      bool
 	isDuplicate bool
+	isPadding   bool
 	Hash        []byte
 	Payload     Payload
 }
@@ -23,8 +24,12 @@ func (n *Node) String() string {
 // verifyNode walks down the tree until hitting a leaf, calculating the hash at each level
 // and returning the resulting hash of Node n.
 func (n *Node) verifyNode() ([]byte, error) {
+	if n.isPadding || n.hashOnly() {
+		return n.Hash, nil
+	}
+
 	if n.isLeaf {
-		return n.Payload.CalculateHash()
+		return n.Tree.hashLeaf(n.Payload)
 	}
 
 	rightBytes, err := n.Right.verifyNode()
@@ -37,14 +42,24 @@ func (n *Node) verifyNode() ([]byte, error) {
 		return nil, err
 	}
 
-	return n.Tree.HashFunc.Calculate(append(leftBytes, rightBytes...))
+	return n.Tree.hashChildren(leftBytes, rightBytes)
 }
 
 // CalculateNodeHash is a helper function that calculates the hash of the node.
 func (n *Node) CalculateNodeHash() ([]byte, error) {
+	if n.isPadding || n.hashOnly() {
+		return n.Hash, nil
+	}
+
 	if n.isLeaf {
-		return n.Payload.CalculateHash()
+		return n.Tree.hashLeaf(n.Payload)
 	}
 
-	return n.Tree.HashFunc.Calculate(append(n.Left.Hash, n.Right.Hash...))
+	return n.Tree.hashChildren(n.Left.Hash, n.Right.Hash)
+}
+
+// hashOnly reports whether the node is a leaf of a tree created from leaf hashes, which holds no payload
+// its hash could be calculated from.
+func (n *Node) hashOnly() bool {
+	return n.isLeaf && n.Payload == nil
 }
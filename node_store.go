@@ -0,0 +1,491 @@
+package merkletree
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// maxFileNodeStoreLevel is the highest level a FileNodeStore holds, the one of the root of a tree whose
+	// size fits an int.
+	maxFileNodeStoreLevel = 64
+	// leafCountLevel is the level of the records of a FileNodeStore holding the number of leaves, which is
+	// held by their index.
+	leafCountLevel = maxFileNodeStoreLevel + 1
+	// logHeaderSize is the size of the start of the log of a FileNodeStore holding the hash size as 8
+	// big-endian bytes.
+	logHeaderSize = 8
+)
+
+// ErrNodeNotFound is returned when a NodeStore holds no hash for a node.
+var ErrNodeNotFound = errors.New("error: node not found in store")
+
+// NodeStore stores the hashes of the nodes of a StoredTree by their position, the level above the leaves
+// and the index within the level, both starting at zero, together with the number of leaves of the tree.
+// A later Put for the same position replaces the hash.
+type NodeStore interface {
+	// Get returns the hash of the node at the given position or ErrNodeNotFound if there is none.
+	Get(level, index int) ([]byte, error)
+	// Put stores the hash of the node at the given position.
+	Put(level, index int, hash []byte) error
+	// LeafCount returns the number of leaves of the tree, zero if none was stored.
+	LeafCount() (int, error)
+	// SetLeafCount stores the number of leaves of the tree.
+	SetLeafCount(n int) error
+}
+
+// nodeKey is the position of a node in a NodeStore.
+type nodeKey struct {
+	level int
+	index int
+}
+
+// MemoryNodeStore is a NodeStore keeping the hashes in memory.
+type MemoryNodeStore struct {
+	hashes    map[nodeKey][]byte
+	leafCount int
+}
+
+// NewMemoryNodeStore creates an empty MemoryNodeStore.
+func NewMemoryNodeStore() *MemoryNodeStore {
+	return &MemoryNodeStore{hashes: make(map[nodeKey][]byte)}
+}
+
+// Get returns a copy of the hash of the node at the given position or ErrNodeNotFound if there is none.
+func (s *MemoryNodeStore) Get(level, index int) ([]byte, error) {
+	hash, ok := s.hashes[nodeKey{level: level, index: index}]
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	return append([]byte{}, hash...), nil
+}
+
+// Put stores a copy of the hash of the node at the given position.
+func (s *MemoryNodeStore) Put(level, index int, hash []byte) error {
+	s.hashes[nodeKey{level: level, index: index}] = append([]byte{}, hash...)
+
+	return nil
+}
+
+// LeafCount returns the number of leaves of the tree, zero if none was stored.
+func (s *MemoryNodeStore) LeafCount() (int, error) {
+	return s.leafCount, nil
+}
+
+// SetLeafCount stores the number of leaves of the tree.
+func (s *MemoryNodeStore) SetLeafCount(n int) error {
+	s.leafCount = n
+
+	return nil
+}
+
+// FileNodeStore is a NodeStore keeping the hashes in an append-only log file in a directory. Every Put appends
+// a record holding the position of the node, its hash and a CRC-32 checksum, so a replaced hash is never
+// overwritten. The offset of the latest record of every node is kept in an index file per level, at a fixed
+// position given by the index of the node, so nothing about the nodes is kept in memory. The number of leaves
+// is appended as a record as well. All hashes have to be of the size the store was created with, which is
+// written at the start of the log.
+type FileNodeStore struct {
+	dir      string
+	hashSize int
+	log      *os.File
+	// end is the size of the log, the offset the next record is appended at.
+	end int64
+	// indexes holds the open index file of each level, nil for levels whose file has not been opened yet.
+	// The index of the number of leaves follows the one of the highest level.
+	indexes []*os.File
+}
+
+// OpenFileNodeStore opens the FileNodeStore in the directory at the given path for hashes of hashSize bytes,
+// creating it if it does not exist. Returns an error if the store was created for another hash size. Records
+// at the end of the log that are partially written or fail their checksum, left by an interrupted Put, are
+// dropped, and Get returns an error for a node whose latest record was dropped until it is put again.
+func OpenFileNodeStore(path string, hashSize int) (*FileNodeStore, error) {
+	if hashSize <= 0 {
+		return nil, fmt.Errorf("error: invalid hash size %d", hashSize)
+	}
+
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, err
+	}
+
+	log, err := os.OpenFile(filepath.Join(path, "log"), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileNodeStore{dir: path, hashSize: hashSize, log: log}
+
+	if err := s.openLog(); err != nil {
+		return nil, errors.Join(err, log.Close())
+	}
+
+	return s, nil
+}
+
+// Get returns the hash of the node at the given position or ErrNodeNotFound if there is none.
+func (s *FileNodeStore) Get(level, index int) ([]byte, error) {
+	if err := checkFileNodePosition(level, index); err != nil {
+		return nil, err
+	}
+
+	record, err := s.latestRecord(level, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if binary.BigEndian.Uint64(record[1:9]) != uint64(index) {
+		return nil, fmt.Errorf("error: no valid record of node %d at level %d", index, level)
+	}
+
+	return record[9 : 9+s.hashSize], nil
+}
+
+// Put stores the hash of the node at the given position by appending it to the log.
+func (s *FileNodeStore) Put(level, index int, hash []byte) error {
+	if len(hash) != s.hashSize {
+		return fmt.Errorf("error: hash of %d bytes for store of %d byte hashes", len(hash), s.hashSize)
+	}
+
+	if err := checkFileNodePosition(level, index); err != nil {
+		return err
+	}
+
+	return s.appendRecord(level, index, uint64(index), hash)
+}
+
+// LeafCount returns the number of leaves of the tree, zero if none was stored.
+func (s *FileNodeStore) LeafCount() (int, error) {
+	record, err := s.latestRecord(leafCountLevel, 0)
+	if errors.Is(err, ErrNodeNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	n := binary.BigEndian.Uint64(record[1:9])
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("error: invalid number of leaves %d in store", n)
+	}
+
+	return int(n), nil
+}
+
+// SetLeafCount stores the number of leaves of the tree by appending it to the log.
+func (s *FileNodeStore) SetLeafCount(n int) error {
+	if n < 0 {
+		return fmt.Errorf("error: invalid number of leaves %d", n)
+	}
+
+	return s.appendRecord(leafCountLevel, 0, uint64(n), make([]byte, s.hashSize))
+}
+
+// Sync commits the written hashes and the number of leaves to stable storage.
+func (s *FileNodeStore) Sync() error {
+	errs := []error{s.log.Sync()}
+
+	for _, file := range s.indexes {
+		if file != nil {
+			errs = append(errs, file.Sync())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the files of the store.
+func (s *FileNodeStore) Close() error {
+	errs := []error{s.log.Close()}
+
+	for _, file := range s.indexes {
+		if file != nil {
+			errs = append(errs, file.Close())
+		}
+	}
+
+	s.indexes = nil
+
+	return errors.Join(errs...)
+}
+
+// openLog checks the hash size of the store against the one at the start of the log, writing it if the log
+// is new, and drops the records at the end of the log that are partially written or fail their checksum.
+func (s *FileNodeStore) openLog() error {
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := binary.BigEndian.AppendUint64(nil, uint64(s.hashSize))
+
+	if info.Size() < logHeaderSize {
+		if err = s.log.Truncate(0); err != nil {
+			return err
+		}
+
+		s.end = logHeaderSize
+		_, err = s.log.WriteAt(header, 0)
+
+		return err
+	}
+
+	stored := make([]byte, logHeaderSize)
+	if _, err = s.log.ReadAt(stored, 0); err != nil {
+		return err
+	}
+
+	if hashSize := binary.BigEndian.Uint64(stored); hashSize != uint64(s.hashSize) {
+		return fmt.Errorf("error: store of %d byte hashes opened for %d byte hashes", hashSize, s.hashSize)
+	}
+
+	recordSize := int64(s.recordSize())
+	s.end = logHeaderSize + (info.Size()-logHeaderSize)/recordSize*recordSize
+
+	for s.end > logHeaderSize {
+		record := make([]byte, recordSize)
+		if _, err = s.log.ReadAt(record, s.end-recordSize); err != nil {
+			return err
+		}
+
+		if s.validRecord(record) {
+			break
+		}
+
+		s.end -= recordSize
+	}
+
+	if s.end == info.Size() {
+		return nil
+	}
+
+	return s.log.Truncate(s.end)
+}
+
+// recordSize returns the size of a record of the log, the level, the index as 8 big-endian bytes, the hash
+// and the CRC-32 checksum of the preceding fields.
+func (s *FileNodeStore) recordSize() int {
+	return 1 + 8 + s.hashSize + crc32.Size
+}
+
+// validRecord reports whether the record matches its checksum.
+func (s *FileNodeStore) validRecord(record []byte) bool {
+	fields := record[:len(record)-crc32.Size]
+
+	return crc32.ChecksumIEEE(fields) == binary.BigEndian.Uint32(record[len(fields):])
+}
+
+// appendRecord appends a record holding the given index and hash at the given level to the log and points
+// the entry at the given slot of the index of the level to it.
+func (s *FileNodeStore) appendRecord(level, slot int, index uint64, hash []byte) error {
+	record := make([]byte, 0, s.recordSize())
+	record = append(record, byte(level))
+	record = binary.BigEndian.AppendUint64(record, index)
+	record = append(record, hash...)
+	record = binary.BigEndian.AppendUint32(record, crc32.ChecksumIEEE(record))
+
+	if _, err := s.log.WriteAt(record, s.end); err != nil {
+		return err
+	}
+
+	offset := s.end
+	s.end += int64(len(record))
+
+	file, err := s.indexFile(level, true)
+	if err != nil {
+		return err
+	}
+
+	// Entries hold the offset of the record plus one, so that zero marks a node without a record.
+	_, err = file.WriteAt(binary.BigEndian.AppendUint64(nil, uint64(offset)+1), int64(slot)*8)
+
+	return err
+}
+
+// latestRecord returns the record the entry at the given slot of the index of the level points to. Returns
+// ErrNodeNotFound if there is none and an error if the record is not one of the level, fails its checksum or
+// was dropped from the log when the store was opened.
+func (s *FileNodeStore) latestRecord(level, slot int) ([]byte, error) {
+	file, err := s.indexFile(level, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNodeNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entry := make([]byte, 8)
+
+	_, err = file.ReadAt(entry, int64(slot)*8)
+	if errors.Is(err, io.EOF) {
+		return nil, ErrNodeNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	offset := binary.BigEndian.Uint64(entry)
+	if offset == 0 {
+		return nil, ErrNodeNotFound
+	}
+
+	record := make([]byte, s.recordSize())
+
+	if start := int64(offset - 1); offset > uint64(s.end) || start+int64(len(record)) > s.end ||
+		(start-logHeaderSize)%int64(len(record)) != 0 {
+		return nil, fmt.Errorf("error: no valid record at slot %d of level %d", slot, level)
+	}
+
+	if _, err = s.log.ReadAt(record, int64(offset-1)); err != nil {
+		return nil, err
+	}
+
+	if !s.validRecord(record) || int(record[0]) != level {
+		return nil, fmt.Errorf("error: no valid record at slot %d of level %d", slot, level)
+	}
+
+	return record, nil
+}
+
+// indexFile returns the index file of the given level, opening it if needed. The file is created if create
+// is set.
+func (s *FileNodeStore) indexFile(level int, create bool) (*os.File, error) {
+	if level < len(s.indexes) && s.indexes[level] != nil {
+		return s.indexes[level], nil
+	}
+
+	flag := os.O_RDWR
+	if create {
+		flag |= os.O_CREATE
+	}
+
+	name := fmt.Sprintf("index-%02d", level)
+	if level == leafCountLevel {
+		name = "index-leaf-count"
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.dir, name), flag, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(s.indexes) <= level {
+		s.indexes = append(s.indexes, nil)
+	}
+
+	s.indexes[level] = file
+
+	return file, nil
+}
+
+// checkFileNodePosition checks that a node at the given position can be held by a FileNodeStore.
+func checkFileNodePosition(level, index int) error {
+	if level < 0 || level > maxFileNodeStoreLevel || index < 0 || int64(index) > math.MaxInt64/8 {
+		return fmt.Errorf("error: invalid node position %d at level %d", index, level)
+	}
+
+	return nil
+}
+
+// nodeCache is a NodeStore keeping the most recently used hashes of another NodeStore in memory. Hashes
+// are written through to the other store.
+type nodeCache struct {
+	store    NodeStore
+	capacity int
+	entries  map[nodeKey]*list.Element
+	// recent orders the cached entries from the most to the least recently used.
+	recent *list.List
+}
+
+// nodeCacheEntry is a hash held by a nodeCache.
+type nodeCacheEntry struct {
+	key  nodeKey
+	hash []byte
+}
+
+// newNodeCache creates a nodeCache holding at most capacity hashes of the given store.
+func newNodeCache(store NodeStore, capacity int) *nodeCache {
+	return &nodeCache{
+		store:    store,
+		capacity: capacity,
+		entries:  make(map[nodeKey]*list.Element),
+		recent:   list.New(),
+	}
+}
+
+// Get returns a copy of the hash of the node at the given position from the cache or from the store.
+func (c *nodeCache) Get(level, index int) ([]byte, error) {
+	key := nodeKey{level: level, index: index}
+
+	if e, ok := c.entries[key]; ok {
+		c.recent.MoveToFront(e)
+
+		return append([]byte{}, e.Value.(*nodeCacheEntry).hash...), nil
+	}
+
+	hash, err := c.store.Get(level, index)
+	if err != nil {
+		return nil, err
+	}
+
+	c.add(key, hash)
+
+	return append([]byte{}, hash...), nil
+}
+
+// Put stores the hash of the node at the given position in the store and a copy of it in the cache.
+func (c *nodeCache) Put(level, index int, hash []byte) error {
+	if err := c.store.Put(level, index, hash); err != nil {
+		return err
+	}
+
+	key := nodeKey{level: level, index: index}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*nodeCacheEntry).hash = append([]byte{}, hash...)
+		c.recent.MoveToFront(e)
+
+		return nil
+	}
+
+	c.add(key, hash)
+
+	return nil
+}
+
+// LeafCount returns the number of leaves stored in the store.
+func (c *nodeCache) LeafCount() (int, error) {
+	return c.store.LeafCount()
+}
+
+// SetLeafCount stores the number of leaves in the store.
+func (c *nodeCache) SetLeafCount(n int) error {
+	return c.store.SetLeafCount(n)
+}
+
+// add caches a copy of the hash, evicting the least recently used one if the cache is full.
+func (c *nodeCache) add(key nodeKey, hash []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	if c.recent.Len() >= c.capacity {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*nodeCacheEntry).key)
+	}
+
+	c.entries[key] = c.recent.PushFront(&nodeCacheEntry{key: key, hash: append([]byte{}, hash...)})
+}
@@ -0,0 +1,174 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestMemoryNodeStore(t *testing.T) {
+	store := merkletree.NewMemoryNodeStore()
+	assertNodeStore(t, store)
+}
+
+func TestFileNodeStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nodes")
+
+	store, err := merkletree.OpenFileNodeStore(dir, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertNodeStore(t, store)
+
+	if err := store.Put(0, 0, []byte{0x01}); err == nil {
+		t.Error("error: expected error for hash of the wrong size")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The replaced hash is appended, the log holds its header and a record for each Put and SetLeafCount.
+	logPath := filepath.Join(dir, "log")
+	recordSize := int64(1 + 8 + sha256.Size + 4)
+
+	if size := 8 + 4*recordSize; fileSize(t, logPath) != size {
+		t.Errorf("error: expected log of %d bytes got %d", size, fileSize(t, logPath))
+	}
+
+	store, err = merkletree.OpenFileNodeStore(dir, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(3, 6, bytes.Repeat([]byte{0x03}, sha256.Size)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A torn last record and a partially written one are dropped when the store is opened again.
+	file, err := os.OpenFile(logPath, os.O_RDWR, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.WriteAt([]byte{0xff}, 8+5*recordSize-6); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.WriteAt([]byte{0x03, 0x00}, 8+5*recordSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = merkletree.OpenFileNodeStore(dir, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if size := 8 + 4*recordSize; fileSize(t, logPath) != size {
+		t.Errorf("error: expected log of %d bytes after dropping its tail got %d", size, fileSize(t, logPath))
+	}
+
+	hash, err := store.Get(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(hash, bytes.Repeat([]byte{0x02}, sha256.Size)) {
+		t.Errorf("error: expected replaced hash to be read back got %x", hash)
+	}
+
+	if n, err := store.LeafCount(); err != nil || n != 7 {
+		t.Errorf("error: expected 7 leaves to be read back got %d, %v", n, err)
+	}
+
+	if _, err := store.Get(3, 4); !errors.Is(err, merkletree.ErrNodeNotFound) {
+		t.Errorf("error: expected ErrNodeNotFound for unset node got %v", err)
+	}
+
+	if _, err := store.Get(3, 6); err == nil || errors.Is(err, merkletree.ErrNodeNotFound) {
+		t.Errorf("error: expected error for node whose record was dropped got %v", err)
+	}
+
+	if err := store.Put(3, 6, bytes.Repeat([]byte{0x03}, sha256.Size)); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err = store.Get(3, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(hash, bytes.Repeat([]byte{0x03}, sha256.Size)) {
+		t.Errorf("error: expected hash put again after its record was dropped got %x", hash)
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info.Size()
+}
+
+func assertNodeStore(t *testing.T, store merkletree.NodeStore) {
+	t.Helper()
+
+	if _, err := store.Get(3, 5); !errors.Is(err, merkletree.ErrNodeNotFound) {
+		t.Errorf("error: expected ErrNodeNotFound got %v", err)
+	}
+
+	for _, b := range []byte{0x01, 0x02} {
+		if err := store.Put(3, 5, bytes.Repeat([]byte{b}, sha256.Size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Put(5, 3, bytes.Repeat([]byte{0x04}, sha256.Size)); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := store.Get(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(hash, bytes.Repeat([]byte{0x02}, sha256.Size)) {
+		t.Errorf("error: expected replaced hash got %x", hash)
+	}
+
+	if n, err := store.LeafCount(); err != nil || n != 0 {
+		t.Errorf("error: expected no leaves got %d, %v", n, err)
+	}
+
+	if err := store.SetLeafCount(7); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := store.LeafCount(); err != nil || n != 7 {
+		t.Errorf("error: expected 7 leaves got %d, %v", n, err)
+	}
+}
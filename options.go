@@ -0,0 +1,256 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// OddNodeStrategy determines how a tree level with an odd number of nodes is completed.
+type OddNodeStrategy int
+
+const (
+	// OddNodeDuplicate pairs the last node of an odd level with a copy of itself. This is the behaviour
+	// of Bitcoin and the default for new trees. Note that a tree ending with a duplicated payload then
+	// has the same root as the tree without it, see CVE-2012-2459.
+	OddNodeDuplicate OddNodeStrategy = iota
+	// OddNodePromote moves the last node of an odd level unchanged to the next level. The resulting
+	// tree has the shape described in RFC 6962, which keeps the roots of its older versions reproducible.
+	OddNodePromote
+	// OddNodeZeroPad pairs the last node of an odd level with the root of a subtree of zero hash leaves of
+	// the same height. The resulting root is the one of the tree padded with zero hash leaves to the next
+	// power of two, without the padding subtrees being stored.
+	OddNodeZeroPad
+)
+
+// DefaultCacheSize is the number of node hashes a StoredTree keeps in memory, unless configured otherwise.
+const DefaultCacheSize = 4096
+
+var (
+	// DefaultLeafPrefix is prepended to the data of every leaf before hashing it, unless configured otherwise.
+	DefaultLeafPrefix = []byte{0x00}
+	// DefaultNodePrefix is prepended to the hashes of the children of every node before hashing them,
+	// unless configured otherwise.
+	DefaultNodePrefix = []byte{0x01}
+)
+
+// Option configures optional behaviour of a MerkleTree. The same options have to be passed to the
+// standalone verifiers of proofs generated from the tree.
+type Option func(*treeConfig)
+
+// treeConfig holds the settings of a tree that can be changed through options.
+type treeConfig struct {
+	oddNodeStrategy OddNodeStrategy
+	// leafPrefix is prepended to the data of a leaf before hashing it. A nil prefix means the leaf
+	// hash is the hash of the payload itself.
+	leafPrefix []byte
+	// nodePrefix is prepended to the hashes of the children of a node before hashing them.
+	nodePrefix []byte
+	// sorted is set when the leaves are ordered by their sort key instead of the order of the payloads.
+	sorted bool
+	// sortKey returns the key a leaf is ordered by. A nil function orders the leaves by their hash.
+	sortKey func(Payload) ([]byte, error)
+	// history is set when a MountainRange keeps the nodes below its peaks.
+	history bool
+	// payloadCodec encodes and decodes the payloads of a serialized tree. A nil codec serializes the
+	// leaf hashes only.
+	payloadCodec PayloadCodec
+	// cacheSize is the number of node hashes a StoredTree keeps in memory.
+	cacheSize int
+}
+
+// WithOddNodeStrategy sets how tree levels with an odd number of nodes are completed.
+func WithOddNodeStrategy(s OddNodeStrategy) Option {
+	return func(c *treeConfig) {
+		c.oddNodeStrategy = s
+	}
+}
+
+// WithDomainSeparation sets the prefixes prepended to the data of a leaf and to the hashes of the children
+// of a node before hashing them. Distinct prefixes make it impossible to present the children of a node
+// as the data of a leaf, which would otherwise allow proving the membership of forged payloads. Neither
+// prefix may be empty or a prefix of the other.
+func WithDomainSeparation(leafPrefix, nodePrefix []byte) Option {
+	return func(c *treeConfig) {
+		c.leafPrefix = append([]byte{}, leafPrefix...)
+		c.nodePrefix = append([]byte{}, nodePrefix...)
+	}
+}
+
+// WithLegacyHashing disables the domain separation of leaf and node hashes. A leaf hash is the hash of its
+// payload and a node hash is the hash of the concatenated hashes of its children, as in trees created
+// before domain separation was introduced. Use it only to reproduce existing merkle roots.
+func WithLegacyHashing() Option {
+	return func(c *treeConfig) {
+		c.leafPrefix = nil
+		c.nodePrefix = nil
+	}
+}
+
+// WithRFC6962 builds the tree as described in RFC 6962 and RFC 9162 for Certificate Transparency logs.
+// Leaves are hashed with the prefix 0x00 and nodes with the prefix 0x01, and the last node of an odd
+// level is promoted. Used with SHA256 and payloads implementing Encoder, roots and proofs are identical
+// to the ones of a Certificate Transparency log holding the encoded payloads.
+func WithRFC6962() Option {
+	return func(c *treeConfig) {
+		c.oddNodeStrategy = OddNodePromote
+		c.leafPrefix = []byte{0x00}
+		c.nodePrefix = []byte{0x01}
+	}
+}
+
+// WithSortedLeaves orders the leaves of the tree by their hash, compared bytewise, instead of the order of
+// the payloads. Sorted trees can prove that a payload is not part of them, see MerkleTree.ProveAbsence.
+func WithSortedLeaves() Option {
+	return func(c *treeConfig) {
+		c.sorted = true
+		c.sortKey = nil
+	}
+}
+
+// WithSortKey orders the leaves of the tree by the key returned for their payload, compared bytewise,
+// instead of the order of the payloads. Leaves with the same key keep the order of their payloads. Sorted
+// trees can prove that no payload with a given key is part of them, see MerkleTree.ProveAbsence.
+func WithSortKey(key func(Payload) ([]byte, error)) Option {
+	return func(c *treeConfig) {
+		c.sorted = true
+		c.sortKey = key
+	}
+}
+
+// WithHistory keeps the nodes below the peaks of a MountainRange, which are needed to build proofs and to
+// look up its roots at earlier sizes. Without it only the peaks are kept. It has no effect on other trees.
+func WithHistory() Option {
+	return func(c *treeConfig) {
+		c.history = true
+	}
+}
+
+// WithPayloadCodec sets the codec the payloads of the tree are written with by MerkleTree.WriteTo and read
+// with by ReadTree. Without it only the leaf hashes are written and read trees hold no payloads.
+func WithPayloadCodec(codec PayloadCodec) Option {
+	return func(c *treeConfig) {
+		c.payloadCodec = codec
+	}
+}
+
+// WithCacheSize sets the number of node hashes a StoredTree keeps in memory, the most recently used ones.
+// It has no effect on other trees.
+func WithCacheSize(n int) Option {
+	return func(c *treeConfig) {
+		c.cacheSize = n
+	}
+}
+
+// newTreeConfig creates the tree settings resulting from applying the given options to the defaults.
+func newTreeConfig(opts []Option) (treeConfig, error) {
+	c := treeConfig{
+		oddNodeStrategy: OddNodeDuplicate,
+		leafPrefix:      DefaultLeafPrefix,
+		nodePrefix:      DefaultNodePrefix,
+		cacheSize:       DefaultCacheSize,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if err := c.validatePrefixes(); err != nil {
+		return treeConfig{}, err
+	}
+
+	return c, nil
+}
+
+// validatePrefixes checks that the leaf and node prefixes are either both unset or both non-empty and
+// distinct.
+func (c treeConfig) validatePrefixes() error {
+	if (c.leafPrefix == nil) != (c.nodePrefix == nil) {
+		return errors.New("error: leaf and node prefixes have to be set together")
+	}
+
+	if c.leafPrefix != nil && (len(c.leafPrefix) == 0 || len(c.nodePrefix) == 0 ||
+		bytes.HasPrefix(c.leafPrefix, c.nodePrefix) || bytes.HasPrefix(c.nodePrefix, c.leafPrefix)) {
+		return errors.New("error: leaf and node prefixes have to be non-empty and distinct")
+	}
+
+	return nil
+}
+
+// treeHeight returns the number of levels above the leaves of a tree with size leaves.
+func (c treeConfig) treeHeight(size int) int {
+	height := 0
+
+	for width := size; width > 1; width = (width + 1) / 2 {
+		height++
+	}
+
+	if height == 0 && c.oddNodeStrategy == OddNodeDuplicate {
+		// A single leaf is paired with its duplicate.
+		return 1
+	}
+
+	return height
+}
+
+// hashLeaf calculates the hash of a leaf holding the given payload. Payloads implementing Encoder are
+// hashed with the hash function of the tree. Other payloads are hashed by their CalculateHash method,
+// except for a HashPayload, which is the hash of the leaf itself. Verifiers reject it, see VerifyLeafHash.
+func (c treeConfig) hashLeaf(hashFunc HashFunc, p Payload) ([]byte, error) {
+	if h, ok := p.(HashPayload); ok {
+		if len(h) != hashFunc().Size() {
+			return nil, fmt.Errorf("error: leaf hash of %d bytes for hash function of %d bytes", len(h), hashFunc().Size())
+		}
+
+		return append([]byte{}, h...), nil
+	}
+
+	if e, ok := p.(Encoder); ok {
+		data, err := e.Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		return hashFunc.sum(c.leafPrefix, data)
+	}
+
+	hash, err := p.CalculateHash()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.leafPrefix == nil {
+		return hash, nil
+	}
+
+	return hashFunc.sum(c.leafPrefix, hash)
+}
+
+// sortKeyOf returns the key a leaf holding the given payload is ordered by in a sorted tree.
+func (c treeConfig) sortKeyOf(hashFunc HashFunc, p Payload) ([]byte, error) {
+	if c.sortKey != nil {
+		return c.sortKey(p)
+	}
+
+	return c.hashLeaf(hashFunc, p)
+}
+
+// hashChildren calculates the hash of a node from the hashes of its children.
+func (c treeConfig) hashChildren(hashFunc HashFunc, left, right []byte) ([]byte, error) {
+	return hashFunc.sum(c.nodePrefix, left, right)
+}
+
+// zeroHash returns the root hash of a subtree of the given height whose leaves are all zero hashes.
+func (c treeConfig) zeroHash(hashFunc HashFunc, height int) ([]byte, error) {
+	hash := make([]byte, hashFunc().Size())
+
+	for i := 0; i < height; i++ {
+		var err error
+
+		if hash, err = c.hashChildren(hashFunc, hash, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,287 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+var oddNodeStrategies = []merkletree.OddNodeStrategy{
+	merkletree.OddNodeDuplicate,
+	merkletree.OddNodePromote,
+	merkletree.OddNodeZeroPad,
+}
+
+func TestNewTreeOddNodeStrategies(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithOddNodeStrategy(strategy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err := tree.VerifyTree()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Errorf("[test case: %s] error: expected tree to be valid with strategy %d", test.testCaseName, strategy)
+			}
+
+			for _, payload := range test.payloads {
+				verifyValidPayload(t, tree, test.testCaseName, payload)
+			}
+
+			verifyInvalidPayload(t, tree, test.testCaseName, test.invalidPayload)
+		}
+	}
+}
+
+func TestNewTreeOddNodeZeroPad(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(),
+			merkletree.WithLegacyHashing(), merkletree.WithOddNodeStrategy(merkletree.OddNodeZeroPad))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var level [][]byte
+
+		for _, payload := range test.payloads {
+			hash, err := payload.CalculateHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			level = append(level, hash)
+		}
+
+		for len(level)&(len(level)-1) != 0 {
+			level = append(level, make([]byte, sha256.Size))
+		}
+
+		for len(level) > 1 {
+			var next [][]byte
+
+			for i := 0; i < len(level); i += 2 {
+				hash := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+				next = append(next, hash[:])
+			}
+
+			level = next
+		}
+
+		if !bytes.Equal(tree.MerkleRootHash, level[0]) {
+			t.Errorf("[test case: %s] error: expected root of tree padded with zero hashes %x got %x",
+				test.testCaseName, level[0], tree.MerkleRootHash)
+		}
+	}
+}
+
+func TestNewTreeOddNodeDuplicateAmbiguity(t *testing.T) {
+	payloads := inputs[4].payloads
+	mutated := append(append([]merkletree.Payload{}, payloads...), payloads[len(payloads)-1])
+
+	for _, strategy := range oddNodeStrategies {
+		tree, err := merkletree.NewTree(payloads, merkletree.SHA256(), merkletree.WithOddNodeStrategy(strategy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mutatedTree, err := merkletree.NewTree(mutated, merkletree.SHA256(), merkletree.WithOddNodeStrategy(strategy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := strategy == merkletree.OddNodeDuplicate
+		if bytes.Equal(tree.MerkleRootHash, mutatedTree.MerkleRootHash) != expected {
+			t.Errorf("error: expected roots to be ambiguous with strategy %d: %t", strategy, expected)
+		}
+	}
+}
+
+func TestNewTreeDomainSeparation(t *testing.T) {
+	for _, test := range inputs {
+		legacy, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithLegacyHashing())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defaults, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		explicit, err := merkletree.NewTree(test.payloads, merkletree.SHA256(),
+			merkletree.WithDomainSeparation(merkletree.DefaultLeafPrefix, merkletree.DefaultNodePrefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		custom, err := merkletree.NewTree(test.payloads, merkletree.SHA256(),
+			merkletree.WithDomainSeparation([]byte("leaf"), []byte("node")))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Equal(defaults.MerkleRootHash, legacy.MerkleRootHash) {
+			t.Errorf("[test case: %s] error: expected domain separated root to differ from legacy root",
+				test.testCaseName)
+		}
+
+		if !bytes.Equal(defaults.MerkleRootHash, explicit.MerkleRootHash) {
+			t.Errorf("[test case: %s] error: expected default prefixes to be used by default", test.testCaseName)
+		}
+
+		if bytes.Equal(defaults.MerkleRootHash, custom.MerkleRootHash) {
+			t.Errorf("[test case: %s] error: expected custom prefixes to change the root", test.testCaseName)
+		}
+
+		for _, tree := range []*merkletree.MerkleTree{legacy, defaults, custom} {
+			ok, err := tree.VerifyTree()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Errorf("[test case: %s] error: expected tree to be valid", test.testCaseName)
+			}
+
+			verifyValidPayload(t, tree, test.testCaseName, test.payloads[0])
+		}
+	}
+}
+
+// calculatedPayload is a payload that does not implement Encoder and is hashed by CalculateHash only.
+type calculatedPayload string
+
+func (c calculatedPayload) CalculateHash() ([]byte, error) {
+	hash := sha256.Sum256([]byte(c))
+
+	return hash[:], nil
+}
+
+func (c calculatedPayload) Equals(other merkletree.Payload) (bool, error) {
+	return c == other, nil
+}
+
+func TestNewTreeHashesLeavesWithTreeHashFunc(t *testing.T) {
+	hashFunc := merkletree.HashFunc(sha512.New)
+
+	for _, test := range []struct {
+		opt    merkletree.Option
+		prefix []byte
+	}{
+		{opt: merkletree.WithDomainSeparation([]byte{0x02}, []byte{0x03}), prefix: []byte{0x02}},
+		{opt: merkletree.WithLegacyHashing()},
+	} {
+		opt, prefix := test.opt, test.prefix
+
+		tree, err := merkletree.NewTree(inputs[0].payloads, hashFunc, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, payload := range inputs[0].payloads {
+			data, err := payload.(merkletree.Encoder).Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := sha512.Sum512(append(append([]byte{}, prefix...), data...))
+			if !bytes.Equal(tree.Leafs[i].Hash, expected[:]) {
+				t.Errorf("error: expected leaf %d to be hashed with the tree hash function, got %x", i, tree.Leafs[i].Hash)
+			}
+
+			proof, err := tree.ProofByIndex(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err := merkletree.VerifyProof(tree.MerkleRootHash, payload, *proof, hashFunc, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Errorf("error: expected proof of leaf %d to be valid", i)
+			}
+		}
+
+		calculated, err := merkletree.NewTree([]calculatedPayload{"a", "b"}, hashFunc, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := calculatedPayload("a").CalculateHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := hash
+		if prefix != nil {
+			sum := sha512.Sum512(append(append([]byte{}, prefix...), hash...))
+			expected = sum[:]
+		}
+
+		if !bytes.Equal(calculated.Leafs[0].Hash, expected) {
+			t.Errorf("error: expected leaf to be hashed from CalculateHash, got %x", calculated.Leafs[0].Hash)
+		}
+	}
+}
+
+func TestNewTreeInvalidDomainSeparation(t *testing.T) {
+	prefixes := [][2][]byte{
+		{{0x01}, {0x01}},
+		{{0x01}, {0x01, 0x02}},
+		{{}, {0x01}},
+		{nil, {0x01}},
+	}
+
+	for _, p := range prefixes {
+		if _, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256(),
+			merkletree.WithDomainSeparation(p[0], p[1])); err == nil {
+			t.Errorf("error: expected error for leaf prefix %x and node prefix %x", p[0], p[1])
+		}
+	}
+}
+
+func TestVerifyProofSecondPreimage(t *testing.T) {
+	leaves := rfc6962Leaves[:4]
+
+	for _, test := range []struct {
+		name      string
+		opt       merkletree.Option
+		forgeable bool
+	}{
+		{name: "legacy", opt: merkletree.WithLegacyHashing(), forgeable: true},
+		{name: "domain separated", opt: merkletree.WithOddNodeStrategy(merkletree.OddNodeDuplicate)},
+	} {
+		tree, err := merkletree.NewTree(leaves, merkletree.SHA256(), test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Present the concatenated children of the left node of the root as a leaf of a two leaf tree.
+		forged := rawPayload(append(append([]byte{}, tree.Leafs[0].Hash...), tree.Leafs[1].Hash...))
+		proof := merkletree.Proof{
+			Siblings:   [][]byte{tree.Root.Right.Hash},
+			Directions: []int64{1},
+			LeafIndex:  0,
+			TreeSize:   2,
+		}
+
+		ok, err := merkletree.VerifyProof(tree.MerkleRootHash, forged, proof, merkletree.SHA256(), test.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok != test.forgeable {
+			t.Errorf("[test case: %s] error: expected forged proof to be valid: %t", test.name, test.forgeable)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// partialTreeHashSize is the size of the hashes of the serialized partial merkle tree format.
+const partialTreeHashSize = 32
+
+// ErrMalformedPartialTree is returned when a partial merkle tree cannot be decoded or does not describe a
+// valid traversal of a tree.
+var ErrMalformedPartialTree = errors.New("error: malformed partial merkle tree")
+
+// PartialMerkleTree is the compact representation of the branches of a tree leading to a set of matched
+// leaves, as sent in the merkleblock messages of Bitcoin (BIP37). The tree is traversed depth first from
+// the root. A flag is recorded for every visited node telling whether it is a matched leaf or the parent
+// of one. The subtree of a node that is not is replaced by its hash, as is a matched leaf, and the
+// children of a node that is are visited. Unlike in the tree it was built from, the root of a single leaf
+// is the leaf hash itself.
+type PartialMerkleTree struct {
+	// TreeSize is the number of leaves in the tree the partial tree was built from.
+	TreeSize int
+	// Hashes are the hashes of the nodes whose subtrees are not visited, in the order of the traversal.
+	Hashes [][]byte
+	// Flags tells for each visited node, in the order of the traversal, whether it is a matched leaf
+	// or the parent of one.
+	Flags []bool
+}
+
+// GetPartialMerkleTree builds a PartialMerkleTree matching the leaves holding the given items. Returns
+// ErrPayloadNotFound if any of the items is not part of the tree.
+func (m *Tree[T]) GetPartialMerkleTree(items []T) (*PartialMerkleTree, error) {
+	indices := make([]int, 0, len(items))
+
+	for _, item := range items {
+		index, err := m.indexOf(item)
+		if err != nil {
+			return nil, err
+		}
+
+		indices = append(indices, index)
+	}
+
+	return m.PartialMerkleTreeByIndex(indices)
+}
+
+// PartialMerkleTreeByIndex builds a PartialMerkleTree matching the leaves at the given positions. The tree
+// has to pair the last node of odd levels with a copy of itself, see OddNodeDuplicate. Returns an
+// *IndexOutOfRangeError if there is no leaf at any of the positions.
+func (m *BaseTree) PartialMerkleTreeByIndex(indices []int) (*PartialMerkleTree, error) {
+	if m.config.oddNodeStrategy != OddNodeDuplicate {
+		return nil, errors.New("error: partial merkle trees require the odd node duplicate strategy")
+	}
+
+	size := m.leafCount()
+	matched := make([]bool, size)
+
+	for _, i := range indices {
+		if i < 0 || i >= size {
+			return nil, &IndexOutOfRangeError{Index: i, Size: size}
+		}
+
+		matched[i] = true
+	}
+
+	p := &PartialMerkleTree{TreeSize: size}
+
+	root := m.Root
+	if size == 1 {
+		root = m.Leafs[0]
+	}
+
+	p.traverseAndBuild(root, partialTreeHeight(size), 0, matched)
+
+	return p, nil
+}
+
+// ExtractMatches checks that the partial tree is a complete traversal of a tree and rebuilds its root.
+// Returns the root together with the hashes and positions of the matched leaves. The options have to match
+// the ones the tree was created with, e.g. WithLegacyHashing for the trees of Bitcoin blocks. Returns
+// ErrMalformedPartialTree if the partial tree is not a valid traversal.
+func (p *PartialMerkleTree) ExtractMatches(hashFunc HashFunc, opts ...Option) ([]byte, [][]byte, []int, error) {
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if c.oddNodeStrategy != OddNodeDuplicate {
+		return nil, nil, nil, errors.New("error: partial merkle trees require the odd node duplicate strategy")
+	}
+
+	if p.TreeSize <= 0 || len(p.Hashes) > p.TreeSize || len(p.Flags) < len(p.Hashes) {
+		return nil, nil, nil, ErrMalformedPartialTree
+	}
+
+	e := &partialTreeExtraction{
+		tree:     p,
+		hashFunc: hashFunc,
+		config:   c,
+	}
+
+	root, err := e.traverseAndExtract(partialTreeHeight(p.TreeSize), 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// All hashes have to be consumed and the unused flags may only fill up the last byte.
+	if (e.flagsUsed+7)/8 != (len(p.Flags)+7)/8 || e.hashesUsed != len(p.Hashes) {
+		return nil, nil, nil, ErrMalformedPartialTree
+	}
+
+	return root, e.matches, e.indices, nil
+}
+
+// MarshalBinary encodes the partial tree in the format of BIP37: the number of leaves as a little-endian
+// uint32, the number of hashes as a CompactSize followed by the hashes, and the number of flag bytes as a
+// CompactSize followed by the flags packed eight per byte, least significant bit first. All hashes have to
+// be 32 bytes long.
+func (p *PartialMerkleTree) MarshalBinary() ([]byte, error) {
+	if p.TreeSize < 0 || uint64(p.TreeSize) > math.MaxUint32 {
+		return nil, errors.New("error: partial merkle tree size out of range")
+	}
+
+	buf := binary.LittleEndian.AppendUint32(nil, uint32(p.TreeSize))
+	buf = appendCompactSize(buf, uint64(len(p.Hashes)))
+
+	for _, h := range p.Hashes {
+		if len(h) != partialTreeHashSize {
+			return nil, errors.New("error: partial merkle tree hashes have to be 32 bytes long")
+		}
+
+		buf = append(buf, h...)
+	}
+
+	flags := make([]byte, (len(p.Flags)+7)/8)
+
+	for i, f := range p.Flags {
+		if f {
+			flags[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	buf = appendCompactSize(buf, uint64(len(flags)))
+
+	return append(buf, flags...), nil
+}
+
+// UnmarshalBinary decodes a partial tree encoded by MarshalBinary. The flags are decoded in multiples of
+// eight. Returns ErrMalformedPartialTree if the data is truncated, has trailing bytes, holds more hashes
+// than leaves or uses a non-canonical CompactSize.
+func (p *PartialMerkleTree) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return ErrMalformedPartialTree
+	}
+
+	hashCount, err := readCompactSize(r)
+	if err != nil {
+		return err
+	}
+
+	if hashCount > uint64(size) || hashCount*partialTreeHashSize > uint64(r.Len()) {
+		return ErrMalformedPartialTree
+	}
+
+	hashes := make([][]byte, hashCount)
+
+	for i := range hashes {
+		hashes[i] = make([]byte, partialTreeHashSize)
+
+		if _, err := io.ReadFull(r, hashes[i]); err != nil {
+			return ErrMalformedPartialTree
+		}
+	}
+
+	flagCount, err := readCompactSize(r)
+	if err != nil {
+		return err
+	}
+
+	if flagCount != uint64(r.Len()) {
+		return ErrMalformedPartialTree
+	}
+
+	flags := make([]bool, 0, flagCount*8)
+
+	for i := uint64(0); i < flagCount; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return ErrMalformedPartialTree
+		}
+
+		for j := 0; j < 8; j++ {
+			flags = append(flags, b&(1<<j) != 0)
+		}
+	}
+
+	p.TreeSize = int(size)
+	p.Hashes = hashes
+	p.Flags = flags
+
+	return nil
+}
+
+// partialTreeExtraction holds the state of the traversal of a PartialMerkleTree rebuilding its root.
+type partialTreeExtraction struct {
+	tree       *PartialMerkleTree
+	hashFunc   HashFunc
+	config     treeConfig
+	flagsUsed  int
+	hashesUsed int
+	matches    [][]byte
+	indices    []int
+}
+
+// traverseAndBuild records the flags and hashes of the node n at position pos of the level height and
+// descends into its children if it is the parent of a matched leaf.
+func (p *PartialMerkleTree) traverseAndBuild(n *Node, height, pos int, matched []bool) {
+	parentOfMatch := false
+
+	for i := pos << height; i < (pos+1)<<height && i < p.TreeSize; i++ {
+		parentOfMatch = parentOfMatch || matched[i]
+	}
+
+	p.Flags = append(p.Flags, parentOfMatch)
+
+	if height == 0 || !parentOfMatch {
+		p.Hashes = append(p.Hashes, append([]byte{}, n.Hash...))
+		return
+	}
+
+	p.traverseAndBuild(n.Left, height-1, pos*2, matched)
+
+	if pos*2+1 < partialTreeWidth(p.TreeSize, height-1) {
+		p.traverseAndBuild(n.Right, height-1, pos*2+1, matched)
+	}
+}
+
+// traverseAndExtract consumes the flags and hashes of the node at position pos of the level height and
+// returns its hash, collecting the matched leaves below it.
+func (e *partialTreeExtraction) traverseAndExtract(height, pos int) ([]byte, error) {
+	if e.flagsUsed >= len(e.tree.Flags) {
+		return nil, ErrMalformedPartialTree
+	}
+
+	parentOfMatch := e.tree.Flags[e.flagsUsed]
+	e.flagsUsed++
+
+	if height == 0 || !parentOfMatch {
+		if e.hashesUsed >= len(e.tree.Hashes) {
+			return nil, ErrMalformedPartialTree
+		}
+
+		hash := e.tree.Hashes[e.hashesUsed]
+		e.hashesUsed++
+
+		if height == 0 && parentOfMatch {
+			e.matches = append(e.matches, hash)
+			e.indices = append(e.indices, pos)
+		}
+
+		return hash, nil
+	}
+
+	left, err := e.traverseAndExtract(height-1, pos*2)
+	if err != nil {
+		return nil, err
+	}
+
+	right := left
+
+	if pos*2+1 < partialTreeWidth(e.tree.TreeSize, height-1) {
+		if right, err = e.traverseAndExtract(height-1, pos*2+1); err != nil {
+			return nil, err
+		}
+
+		// Identical children only appear in trees with a repeated run of leaves, see ErrMutatedMerkleTree.
+		if bytes.Equal(left, right) {
+			return nil, ErrMalformedPartialTree
+		}
+	}
+
+	return e.config.hashChildren(e.hashFunc, left, right)
+}
+
+// partialTreeWidth returns the number of nodes at the level height of a tree with size leaves.
+func partialTreeWidth(size, height int) int {
+	return (size + (1 << height) - 1) >> height
+}
+
+// partialTreeHeight returns the number of levels above the leaves of a partial tree with size leaves.
+func partialTreeHeight(size int) int {
+	height := 0
+
+	for partialTreeWidth(size, height) > 1 {
+		height++
+	}
+
+	return height
+}
+
+// appendCompactSize appends the Bitcoin CompactSize encoding of v to buf.
+func appendCompactSize(buf []byte, v uint64) []byte {
+	switch {
+	case v < 0xfd:
+		return append(buf, byte(v))
+	case v <= math.MaxUint16:
+		return binary.LittleEndian.AppendUint16(append(buf, 0xfd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.LittleEndian.AppendUint32(append(buf, 0xfe), uint32(v))
+	default:
+		return binary.LittleEndian.AppendUint64(append(buf, 0xff), v)
+	}
+}
+
+// readCompactSize reads a Bitcoin CompactSize from r. Returns ErrMalformedPartialTree if it is truncated
+// or not encoded in the shortest possible form.
+func readCompactSize(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, ErrMalformedPartialTree
+	}
+
+	var width int
+
+	switch prefix {
+	case 0xfd:
+		width = 2
+	case 0xfe:
+		width = 4
+	case 0xff:
+		width = 8
+	default:
+		return uint64(prefix), nil
+	}
+
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b[:width]); err != nil {
+		return 0, ErrMalformedPartialTree
+	}
+
+	v := binary.LittleEndian.Uint64(b)
+	if len(appendCompactSize(nil, v)) != 1+width {
+		return 0, ErrMalformedPartialTree
+	}
+
+	return v, nil
+}
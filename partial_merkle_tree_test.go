@@ -0,0 +1,196 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// The expected encoding is computed independently in Python following CPartialMerkleTree of Bitcoin Core,
+// for the first three transactions of block 100000 with the second one matched.
+const partialMerkleTreeVector = "0300000003" +
+	"876dd0a3ef4a2816ffd1c12ab649825a958b0ff3bb3d6f3e1250f13ddbf0148c" +
+	"c40297f730dd7b5a99567eb8d27b78758f607507c52292d02d4031895b52f2ff" +
+	"617cd46fbab76d8048d101187d49ec0df9ede92464ed67eae2bd8e59f1423328" +
+	"010b"
+
+func TestPartialMerkleTreeBitcoin(t *testing.T) {
+	txids := decodeTxids(t, bitcoinBlocks[1].txids)[:3]
+
+	tree, err := merkletree.NewTreeFromHashes(txids, merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partial, err := tree.GetPartialMerkleTree([]merkletree.Payload{merkletree.HashPayload(txids[1])})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := partial.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hex.EncodeToString(data) != partialMerkleTreeVector {
+		t.Errorf("error: expected encoding %s got %x", partialMerkleTreeVector, data)
+	}
+
+	var decoded merkletree.PartialMerkleTree
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	root, matches, indices, err := decoded.ExtractMatches(merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRoot, err := merkletree.BitcoinMerkleRoot(txids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("error: expected merkle root %x got %x", expectedRoot, root)
+	}
+
+	if !reflect.DeepEqual(matches, [][]byte{txids[1]}) || !reflect.DeepEqual(indices, []int{1}) {
+		t.Errorf("error: expected match of txid %x at 1 got %x at %v", txids[1], matches, indices)
+	}
+}
+
+func TestPartialMerkleTreeRoundTrip(t *testing.T) {
+	payloads := inputs[0].payloads
+	matchSets := [][]int{{}, {0}, {2}, {0, 4}, {1, 2, 3}, {0, 1, 2, 3, 4, 5, 6, 7}}
+
+	for size := 1; size <= len(payloads); size++ {
+		tree, err := merkletree.NewTree(payloads[:size], merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, matchSet := range matchSets {
+			var indices []int
+
+			for _, i := range matchSet {
+				if i < size {
+					indices = append(indices, i)
+				}
+			}
+
+			partial, err := tree.PartialMerkleTreeByIndex(indices)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := partial.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded merkletree.PartialMerkleTree
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+
+			root, matches, matchedIndices, err := decoded.ExtractMatches(merkletree.SHA256())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedRoot := tree.MerkleRootHash
+			if size == 1 {
+				expectedRoot = tree.Leafs[0].Hash
+			}
+
+			if !bytes.Equal(root, expectedRoot) {
+				t.Errorf("[size: %d, matched: %v] error: expected root %x got %x", size, indices, expectedRoot, root)
+			}
+
+			if len(matchedIndices) != len(indices) {
+				t.Fatalf("[size: %d, matched: %v] error: expected matches got %v", size, indices, matchedIndices)
+			}
+
+			for i, index := range indices {
+				if matchedIndices[i] != index || !bytes.Equal(matches[i], tree.Leafs[index].Hash) {
+					t.Errorf("[size: %d, matched: %v] error: expected match of leaf %d got %d",
+						size, indices, index, matchedIndices[i])
+				}
+			}
+		}
+	}
+}
+
+func TestPartialMerkleTreeMalformed(t *testing.T) {
+	vector, err := hex.DecodeString(partialMerkleTreeVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated":      vector[:len(vector)-1],
+		"trailing byte":  append(append([]byte{}, vector...), 0x00),
+		"non-canonical":  append(append(append([]byte{}, vector[:4]...), 0xfd, 0x03, 0x00), vector[5:]...),
+		"too many":       append([]byte{0x01, 0x00, 0x00, 0x00}, vector[4:]...),
+		"no transaction": {0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		var decoded merkletree.PartialMerkleTree
+
+		err := decoded.UnmarshalBinary(data)
+		if err == nil {
+			_, _, _, err = decoded.ExtractMatches(merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+		}
+
+		if !errors.Is(err, merkletree.ErrMalformedPartialTree) {
+			t.Errorf("[test case: %s] error: expected ErrMalformedPartialTree got %v", name, err)
+		}
+	}
+
+	var decoded merkletree.PartialMerkleTree
+	if err := decoded.UnmarshalBinary(vector); err != nil {
+		t.Fatal(err)
+	}
+
+	root, _, indices, err := decoded.ExtractMatches(merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first five flags are used, the others pad the flag byte and are ignored.
+	for i := 0; i < 5; i++ {
+		flipped := decoded
+		flipped.Flags = append([]bool{}, decoded.Flags...)
+		flipped.Flags[i] = !flipped.Flags[i]
+
+		flippedRoot, _, flippedIndices, err := flipped.ExtractMatches(
+			merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+		if err == nil && bytes.Equal(flippedRoot, root) && reflect.DeepEqual(flippedIndices, indices) {
+			t.Errorf("error: expected flipped flag %d to change the result", i)
+		}
+	}
+}
+
+func TestPartialMerkleTreeMutated(t *testing.T) {
+	txids := decodeTxids(t, bitcoinBlocks[1].txids)[:3]
+	mutated := append(append([][]byte{}, txids...), txids[2])
+
+	tree, err := merkletree.NewTreeFromHashes(mutated, merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partial, err := tree.PartialMerkleTreeByIndex([]int{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, err = partial.ExtractMatches(merkletree.DoubleSHA256(), merkletree.WithLegacyHashing())
+	if !errors.Is(err, merkletree.ErrMalformedPartialTree) {
+		t.Errorf("error: expected ErrMalformedPartialTree got %v", err)
+	}
+}
@@ -1,7 +1,7 @@
 package merkletree
 
 import (
-	"encoding/json"
+	"bytes"
 	"reflect"
 )
 
@@ -12,25 +12,106 @@ type Payload interface {
 	Equals(other Payload) (bool, error)
 }
 
+// Encoder is implemented by payloads that can expose the raw bytes they are hashed from. Trees hash these
+// bytes with their own hash function instead of calling CalculateHash, so all the hashes of a tree are
+// calculated with the same hash function. With a leaf prefix the bytes are hashed after the prefix, e.g.
+// to produce the same leaf hashes as a Certificate Transparency log.
+type Encoder interface {
+	Encode() ([]byte, error)
+}
+
 // PaymentTransactionPayload implements the Payload interface and represents the Payload stored in the tree.
 // This implementation represents a payment transaction.
 type PaymentTransactionPayload struct {
-	SenderAddress   string  `json:"sender_address"`
-	ReceiverAddress string  `json:"receiver_address"`
-	Amount          float64 `json:"amount"`
+	SenderAddress   string `json:"sender_address"`
+	ReceiverAddress string `json:"receiver_address"`
+	// Amount is the transferred amount in minor units of the currency, e.g. satoshis.
+	Amount int64 `json:"amount"`
 }
 
-// CalculateHash calculates the hash of the values of a PaymentTransactionPayload.
+// Encode returns the canonical encoding of a PaymentTransactionPayload: the sender address, the receiver
+// address and the amount written in this order to a CanonicalEncoder.
+func (t PaymentTransactionPayload) Encode() ([]byte, error) {
+	var e CanonicalEncoder
+
+	e.WriteString(t.SenderAddress)
+	e.WriteString(t.ReceiverAddress)
+	e.WriteInt64(t.Amount)
+
+	return e.Bytes()
+}
+
+// CalculateHash calculates the SHA256 hash of the canonical encoding of a PaymentTransactionPayload. Trees
+// do not call it, as they hash the encoding with their own hash function.
 func (t PaymentTransactionPayload) CalculateHash() ([]byte, error) {
-	jsonBytes, err := json.Marshal(t)
+	data, err := t.Encode()
 	if err != nil {
 		return nil, err
 	}
 
-	return SHA256().Calculate(jsonBytes)
+	return SHA256().Calculate(data)
 }
 
 // Equals checks if two PaymentTransactionPayloads are equal.
 func (t PaymentTransactionPayload) Equals(other Payload) (bool, error) {
 	return reflect.DeepEqual(t, other), nil
 }
+
+// BytesPayload is a Payload holding raw bytes, e.g. a file chunk or a serialized message. Trees hash the
+// bytes themselves.
+type BytesPayload []byte
+
+// Encode returns the bytes of a BytesPayload.
+func (b BytesPayload) Encode() ([]byte, error) {
+	return b, nil
+}
+
+// CalculateHash calculates the SHA256 hash of the bytes of a BytesPayload.
+func (b BytesPayload) CalculateHash() ([]byte, error) {
+	return SHA256().Calculate(b)
+}
+
+// Equals checks if the other payload is a BytesPayload with the same bytes.
+func (b BytesPayload) Equals(other Payload) (bool, error) {
+	o, ok := other.(BytesPayload)
+
+	return ok && bytes.Equal(b, o), nil
+}
+
+// StringPayload is a Payload holding a string. Trees hash its UTF-8 bytes.
+type StringPayload string
+
+// Encode returns the UTF-8 bytes of a StringPayload.
+func (s StringPayload) Encode() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// CalculateHash calculates the SHA256 hash of the UTF-8 bytes of a StringPayload.
+func (s StringPayload) CalculateHash() ([]byte, error) {
+	return SHA256().Calculate([]byte(s))
+}
+
+// Equals checks if the other payload is the same StringPayload.
+func (s StringPayload) Equals(other Payload) (bool, error) {
+	o, ok := other.(StringPayload)
+
+	return ok && s == o, nil
+}
+
+// HashPayload is a Payload holding an already calculated leaf hash. Trees use it as the hash of its leaf
+// as it is, without hashing it again or prepending a leaf prefix, so it has to be calculated the way the
+// tree would. Its length has to be the size of the hash function of the tree. It is only used to build trees,
+// the verifiers reject it, see VerifyLeafHash.
+type HashPayload []byte
+
+// CalculateHash returns the hash held by a HashPayload.
+func (h HashPayload) CalculateHash() ([]byte, error) {
+	return h, nil
+}
+
+// Equals checks if the other payload is a HashPayload with the same hash.
+func (h HashPayload) Equals(other Payload) (bool, error) {
+	o, ok := other.(HashPayload)
+
+	return ok && bytes.Equal(h, o), nil
+}
@@ -0,0 +1,114 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestNewTreeFromBytes(t *testing.T) {
+	var data [][]byte
+
+	for _, leaf := range rfc6962Leaves {
+		data = append(data, leaf.(rawPayload))
+	}
+
+	for size := 1; size <= len(data); size++ {
+		tree, err := merkletree.NewTreeFromBytes(data[:size], merkletree.SHA256(), merkletree.WithRFC6962())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(tree.MerkleRootHash) != rfc6962Roots[size-1] {
+			t.Errorf("error: expected root of size %d equal to %s got %x",
+				size, rfc6962Roots[size-1], tree.MerkleRootHash)
+		}
+
+		for i := 0; i < size; i++ {
+			leaf, err := tree.Leaf(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(leaf, data[i]) {
+				t.Errorf("error: expected leaf %d equal to %x got %x", i, data[i], leaf)
+			}
+
+			proof, err := tree.GetProof(leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyProof(t, "bytes", tree.MerkleRootHash, merkletree.BytesPayload(data[i]), *proof, true,
+				merkletree.WithRFC6962())
+		}
+	}
+}
+
+func TestStringPayload(t *testing.T) {
+	items := []merkletree.StringPayload{"", "\x00", "\x10", "\x20\x21"}
+
+	tree, err := merkletree.NewTree(items, merkletree.SHA256(), merkletree.WithRFC6962())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hex.EncodeToString(tree.MerkleRootHash) != rfc6962Roots[len(items)-1] {
+		t.Errorf("error: expected root equal to %s got %x", rfc6962Roots[len(items)-1], tree.MerkleRootHash)
+	}
+
+	if _, err := tree.IndexOf("\x10"); err != nil {
+		t.Errorf("error: expected string payload to be found: %v", err)
+	}
+
+	ok, err := merkletree.StringPayload("a").Equals(merkletree.BytesPayload("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Error("error: expected payloads of different types not to be equal")
+	}
+}
+
+func TestHashPayload(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var hashes []merkletree.HashPayload
+
+			for _, n := range tree.Leafs[:len(test.payloads)] {
+				hashes = append(hashes, n.Hash)
+			}
+
+			hashTree, err := merkletree.NewTree(hashes, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(hashTree.MerkleRootHash, tree.MerkleRootHash) {
+				t.Errorf("[test case: %s] error: expected hash equal to %x got %x",
+					test.testCaseName, tree.MerkleRootHash, hashTree.MerkleRootHash)
+			}
+
+			proof, err := hashTree.GetProof(hashes[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyProof(t, test.testCaseName, tree.MerkleRootHash, test.payloads[0], *proof, true, opt)
+		}
+	}
+
+	if _, err := merkletree.NewTree([]merkletree.HashPayload{{0x01}}, merkletree.SHA256()); err == nil {
+		t.Error("error: expected error for leaf hash of the wrong size")
+	}
+}
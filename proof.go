@@ -0,0 +1,259 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrPayloadNotFound is returned when a proof is requested for a payload that is not part of the tree.
+	ErrPayloadNotFound = errors.New("error: payload not found in tree")
+	// ErrLeafHashPayload is returned when a HashPayload is verified as a payload, see VerifyLeafHash.
+	ErrLeafHashPayload = errors.New("error: leaf hashes cannot be verified as payloads, use VerifyLeafHash")
+)
+
+// IndexOutOfRangeError is returned when a leaf is requested by a position outside of the tree.
+type IndexOutOfRangeError struct {
+	Index int
+	Size  int
+}
+
+// Error returns the description of the error.
+func (e *IndexOutOfRangeError) Error() string {
+	return fmt.Sprintf("error: leaf index %d out of range for tree of size %d", e.Index, e.Size)
+}
+
+// Proof is a self-contained inclusion proof for a single leaf of a MerkleTree. It holds everything
+// needed to recompute the merkle root from the leaf, so it can be verified by a party that only
+// knows the merkle root and not the payloads of the tree.
+type Proof struct {
+	// LeafHash is the hash of the proven leaf.
+	LeafHash []byte
+	// Siblings are the hashes of the sibling nodes on the path from the leaf to the root.
+	Siblings [][]byte
+	// Directions tells for each sibling whether it is the right (1) or the left (0) child.
+	Directions []int64
+	// LeafIndex is the position of the proven leaf in the tree.
+	LeafIndex int
+	// TreeSize is the number of leaves in the tree the proof was generated from.
+	TreeSize int
+	// HashAlgorithm is the name of the hash algorithm of the tree or empty if it is not a known one.
+	HashAlgorithm string
+}
+
+// pathStep describes a single level of the path from a leaf to the root of the tree.
+type pathStep struct {
+	// direction is the position of the sibling, 1 if it is the right child and 0 if it is the left.
+	direction int64
+	// duplicate is set when the node has no sibling and is paired with a copy of itself.
+	duplicate bool
+	// padding is set when the node has no sibling and is paired with a subtree of zero hashes.
+	padding bool
+	// level is the height of the node on the path above the leaves of the tree.
+	level int
+}
+
+// GetProof builds a Proof for the given item. Returns ErrPayloadNotFound if the item is not
+// part of the tree.
+func (m *Tree[T]) GetProof(item T) (*Proof, error) {
+	index, err := m.indexOf(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.proofForLeaf(index), nil
+}
+
+// ProofByIndex builds a Proof for the leaf at the given position. Returns an *IndexOutOfRangeError if
+// there is no leaf at the position.
+func (m *BaseTree) ProofByIndex(i int) (*Proof, error) {
+	if size := m.leafCount(); i < 0 || i >= size {
+		return nil, &IndexOutOfRangeError{Index: i, Size: size}
+	}
+
+	return m.proofForLeaf(i), nil
+}
+
+// ProofByLeafHash builds a Proof for the first leaf with the given hash. Returns ErrLeafNotFound if
+// no leaf of the tree has the hash.
+func (m *BaseTree) ProofByLeafHash(h []byte) (*Proof, error) {
+	indices := m.leafIndex[string(h)]
+	if len(indices) == 0 {
+		return nil, ErrLeafNotFound
+	}
+
+	return m.proofForLeaf(indices[0]), nil
+}
+
+// proofForLeaf traces the path from the leaf at the given index up to the root of the tree.
+func (m *BaseTree) proofForLeaf(index int) *Proof {
+	current := m.Leafs[index]
+	proof := &Proof{
+		LeafHash:      current.Hash,
+		LeafIndex:     index,
+		TreeSize:      m.leafCount(),
+		HashAlgorithm: m.hashAlgorithm,
+	}
+
+	for parent := current.Parent; parent != nil; parent = parent.Parent {
+		if parent.Left == current {
+			proof.Siblings = append(proof.Siblings, parent.Right.Hash)
+			proof.Directions = append(proof.Directions, 1) // right leaf
+		} else {
+			proof.Siblings = append(proof.Siblings, parent.Left.Hash)
+			proof.Directions = append(proof.Directions, 0) // left leaf
+		}
+
+		current = parent
+	}
+
+	return proof
+}
+
+// VerifyProof checks that the payload is part of a tree with the given merkle root by recomputing the
+// root from the payload and the proof alone. The shape of the path is checked against the leaf index
+// and the tree size of the proof, so a valid proof also binds the payload to its position in the tree.
+// The options have to match the ones the tree was created with. Returns true if valid and false otherwise.
+// Returns ErrLeafHashPayload for a HashPayload, see VerifyLeafHash.
+func VerifyProof(root []byte, payload Payload, proof Proof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	if err := checkHashAlgorithm(proof.HashAlgorithm, hashFunc); err != nil {
+		return false, err
+	}
+
+	if err := rejectHashPayload(payload); err != nil {
+		return false, err
+	}
+
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := c.hashLeaf(hashFunc, payload)
+	if err != nil {
+		return false, err
+	}
+
+	return verifyLeafPath(root, hash, proof, hashFunc, c)
+}
+
+// VerifyLeafHash checks that the leaf hash is part of a tree with the given merkle root and number of leaves,
+// e.g. for trees created with NewTreeFromHashes. A hash cannot tell a leaf from an inner node, so the tree
+// size has to be known to the verifier from a trusted source, not taken from the proof. The proof is only
+// valid for a tree of that size, which fixes the depth of the leaf. The options have to match the ones the
+// tree was created with. Returns true if valid and false otherwise.
+func VerifyLeafHash(
+	root, leafHash []byte, treeSize int, proof Proof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	if err := checkHashAlgorithm(proof.HashAlgorithm, hashFunc); err != nil {
+		return false, err
+	}
+
+	if len(leafHash) != hashFunc().Size() {
+		return false, fmt.Errorf(
+			"error: leaf hash of %d bytes for hash function of %d bytes", len(leafHash), hashFunc().Size())
+	}
+
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	if proof.TreeSize != treeSize {
+		return false, nil
+	}
+
+	return verifyLeafPath(root, leafHash, proof, hashFunc, c)
+}
+
+// verifyLeafPath recomputes the root from the hash of a leaf and its proof and compares it to the given root.
+func verifyLeafPath(root, hash []byte, proof Proof, hashFunc HashFunc, c treeConfig) (bool, error) {
+	if proof.LeafHash != nil && !bytes.Equal(hash, proof.LeafHash) {
+		return false, nil
+	}
+
+	steps, err := pathSteps(proof.LeafIndex, proof.TreeSize, c)
+	if err != nil {
+		return false, err
+	}
+
+	if len(proof.Siblings) != len(steps) || len(proof.Directions) != len(steps) {
+		return false, nil
+	}
+
+	for i, step := range steps {
+		sibling := proof.Siblings[i]
+
+		if proof.Directions[i] != step.direction {
+			return false, nil
+		}
+
+		if step.duplicate && !bytes.Equal(sibling, hash) {
+			return false, nil
+		}
+
+		if step.padding {
+			zeroHash, err := c.zeroHash(hashFunc, step.level)
+			if err != nil {
+				return false, err
+			}
+
+			if !bytes.Equal(sibling, zeroHash) {
+				return false, nil
+			}
+		}
+
+		if step.direction == 1 {
+			hash, err = c.hashChildren(hashFunc, hash, sibling)
+		} else {
+			hash, err = c.hashChildren(hashFunc, sibling, hash)
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return bytes.Equal(hash, root), nil
+}
+
+// rejectHashPayload returns ErrLeafHashPayload if the payload to be verified is a HashPayload.
+func rejectHashPayload(p Payload) error {
+	if _, ok := p.(HashPayload); ok {
+		return ErrLeafHashPayload
+	}
+
+	return nil
+}
+
+// pathSteps computes the shape of the path from the leaf at the given index up to the root of a
+// tree with size leaves. Levels where the node on the path is promoted have no step.
+func pathSteps(index, size int, c treeConfig) ([]pathStep, error) {
+	if index < 0 || index >= size {
+		return nil, &IndexOutOfRangeError{Index: index, Size: size}
+	}
+
+	var steps []pathStep
+
+	height := c.treeHeight(size)
+
+	for level, width := 0, size; level < height; level, width = level+1, (width+1)/2 {
+		unpaired := index%2 == 0 && index == width-1
+
+		switch {
+		case unpaired && c.oddNodeStrategy == OddNodePromote:
+		case unpaired && c.oddNodeStrategy == OddNodeZeroPad:
+			steps = append(steps, pathStep{direction: 1, padding: true, level: level})
+		case unpaired:
+			steps = append(steps, pathStep{direction: 1, duplicate: true, level: level})
+		case index%2 == 1:
+			steps = append(steps, pathStep{direction: 0, level: level})
+		default:
+			steps = append(steps, pathStep{direction: 1, level: level})
+		}
+
+		index /= 2
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,406 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// proofFormatVersion is the version of the JSON and binary encodings of a Proof.
+	proofFormatVersion = 1
+	// maxProofPathLength is the largest number of siblings a decoded Proof may have, enough for any tree
+	// whose size fits an int.
+	maxProofPathLength = 64
+	// maxProofHashSize is the largest hash size in bytes a decoded Proof may have.
+	maxProofHashSize = 64
+	// maxEncodedProofSize is the largest encoded Proof in bytes that is decoded.
+	maxEncodedProofSize = 64 << 10
+)
+
+// ErrMalformedProof is returned when an encoded Proof cannot be decoded.
+var ErrMalformedProof = errors.New("error: malformed proof")
+
+// proofJSON is the JSON representation of a Proof.
+type proofJSON struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm"`
+	LeafIndex int             `json:"leaf_index"`
+	TreeSize  int             `json:"tree_size"`
+	LeafHash  string          `json:"leaf_hash,omitempty"`
+	Path      []proofStepJSON `json:"path"`
+}
+
+// proofStepJSON is the JSON representation of a sibling on the path of a Proof.
+type proofStepJSON struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"`
+}
+
+// MarshalJSON encodes the proof as a JSON object with the format version, the hash algorithm, the leaf
+// index, the tree size, the hex encoded leaf hash and the path of hex encoded siblings from the leaf to the
+// root, each with its position, "left" or "right".
+func (p Proof) MarshalJSON() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	v := proofJSON{
+		Version:   proofFormatVersion,
+		Algorithm: p.HashAlgorithm,
+		LeafIndex: p.LeafIndex,
+		TreeSize:  p.TreeSize,
+		LeafHash:  hex.EncodeToString(p.LeafHash),
+		Path:      make([]proofStepJSON, 0, len(p.Siblings)),
+	}
+
+	for i, s := range p.Siblings {
+		step := proofStepJSON{Hash: hex.EncodeToString(s), Position: "left"}
+		if p.Directions[i] == 1 {
+			step.Position = "right"
+		}
+
+		v.Path = append(v.Path, step)
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a proof encoded by MarshalJSON. Unknown fields, field names differing in case,
+// trailing data, unknown versions and positions, invalid hex, hashes of different sizes and oversized proofs
+// are rejected with ErrMalformedProof.
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	if len(data) > maxEncodedProofSize {
+		return fmt.Errorf("%w: proof of %d bytes", ErrMalformedProof, len(data))
+	}
+
+	if err := checkProofJSONFields(data); err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var v proofJSON
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+	}
+
+	if v.Version != proofFormatVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrMalformedProof, v.Version)
+	}
+
+	proof := Proof{
+		LeafIndex:     v.LeafIndex,
+		TreeSize:      v.TreeSize,
+		HashAlgorithm: v.Algorithm,
+	}
+
+	if v.LeafHash != "" {
+		leafHash, err := hex.DecodeString(v.LeafHash)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+		}
+
+		proof.LeafHash = leafHash
+	}
+
+	if len(v.Path) > maxProofPathLength {
+		return fmt.Errorf("%w: path of %d siblings", ErrMalformedProof, len(v.Path))
+	}
+
+	for _, step := range v.Path {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+		}
+
+		var direction int64
+
+		switch step.Position {
+		case "left":
+			direction = 0
+		case "right":
+			direction = 1
+		default:
+			return fmt.Errorf("%w: unknown position %q", ErrMalformedProof, step.Position)
+		}
+
+		proof.Siblings = append(proof.Siblings, sibling)
+		proof.Directions = append(proof.Directions, direction)
+	}
+
+	if err := proof.validate(); err != nil {
+		return err
+	}
+
+	*p = proof
+
+	return nil
+}
+
+// checkProofJSONFields checks that the data holds a single JSON object whose field names, and the ones of
+// the steps of its path, are exactly the ones written by MarshalJSON. The JSON decoder alone matches field
+// names regardless of case and ignores data following the first value.
+func checkProofJSONFields(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var fields map[string]json.RawMessage
+	if err := dec.Decode(&fields); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: trailing data", ErrMalformedProof)
+	}
+
+	if err := checkJSONFieldNames(fields, "version", "algorithm", "leaf_index", "tree_size", "leaf_hash",
+		"path"); err != nil {
+		return err
+	}
+
+	path, ok := fields["path"]
+	if !ok {
+		return nil
+	}
+
+	var steps []map[string]json.RawMessage
+	if err := json.Unmarshal(path, &steps); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+	}
+
+	for _, step := range steps {
+		if err := checkJSONFieldNames(step, "hash", "position"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkJSONFieldNames returns ErrMalformedProof if any of the fields is not named exactly as one of the
+// given names.
+func checkJSONFieldNames(fields map[string]json.RawMessage, names ...string) error {
+	for field := range fields {
+		known := false
+
+		for _, name := range names {
+			known = known || field == name
+		}
+
+		if !known {
+			return fmt.Errorf("%w: unknown field %q", ErrMalformedProof, field)
+		}
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the proof in a compact binary format: the format version as a byte, the hash
+// algorithm name prefixed with its length as a byte, the leaf index and the tree size as 8 big-endian
+// bytes, the hash size and whether the leaf hash is included as a byte each, the leaf hash, the number
+// of siblings as a byte, their positions packed eight per byte, least significant bit first and set for
+// right siblings, and the siblings.
+func (p Proof) MarshalBinary() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	if len(p.HashAlgorithm) > math.MaxUint8 {
+		return nil, errors.New("error: hash algorithm name too long")
+	}
+
+	hashSize := p.hashSize()
+
+	buf := []byte{proofFormatVersion, byte(len(p.HashAlgorithm))}
+	buf = append(buf, p.HashAlgorithm...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.LeafIndex))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(p.TreeSize))
+
+	if p.LeafHash == nil {
+		buf = append(buf, byte(hashSize), 0)
+	} else {
+		buf = append(buf, byte(hashSize), 1)
+		buf = append(buf, p.LeafHash...)
+	}
+
+	buf = append(buf, byte(len(p.Siblings)))
+	positions := make([]byte, (len(p.Siblings)+7)/8)
+
+	for i, d := range p.Directions {
+		if d == 1 {
+			positions[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	buf = append(buf, positions...)
+
+	for _, s := range p.Siblings {
+		buf = append(buf, s...)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary. Truncated data, trailing bytes, unknown
+// versions, set padding bits and oversized proofs are rejected with ErrMalformedProof.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) > maxEncodedProofSize {
+		return fmt.Errorf("%w: proof of %d bytes", ErrMalformedProof, len(data))
+	}
+
+	r := bytes.NewReader(data)
+	next := func(n int) []byte {
+		if n > r.Len() {
+			return nil
+		}
+
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil
+		}
+
+		return b
+	}
+
+	header := next(2)
+	if header == nil || header[0] != proofFormatVersion {
+		return fmt.Errorf("%w: unsupported version", ErrMalformedProof)
+	}
+
+	algorithm := next(int(header[1]))
+	sizes := next(8 + 8 + 2)
+
+	if algorithm == nil || sizes == nil {
+		return fmt.Errorf("%w: truncated", ErrMalformedProof)
+	}
+
+	leafIndex, treeSize := binary.BigEndian.Uint64(sizes[:8]), binary.BigEndian.Uint64(sizes[8:16])
+	hashSize, hasLeafHash := int(sizes[16]), sizes[17]
+
+	if leafIndex > math.MaxInt || treeSize > math.MaxInt || hasLeafHash > 1 {
+		return fmt.Errorf("%w: invalid header", ErrMalformedProof)
+	}
+
+	proof := Proof{
+		LeafIndex:     int(leafIndex),
+		TreeSize:      int(treeSize),
+		HashAlgorithm: string(algorithm),
+	}
+
+	if hasLeafHash == 1 {
+		if proof.LeafHash = next(hashSize); proof.LeafHash == nil {
+			return fmt.Errorf("%w: truncated", ErrMalformedProof)
+		}
+	}
+
+	count, err := r.ReadByte()
+	if err != nil || count > maxProofPathLength {
+		return fmt.Errorf("%w: invalid number of siblings", ErrMalformedProof)
+	}
+
+	positions := next((int(count) + 7) / 8)
+	if positions == nil {
+		return fmt.Errorf("%w: truncated", ErrMalformedProof)
+	}
+
+	for i := 0; i < len(positions)*8; i++ {
+		right := positions[i/8]&(1<<(i%8)) != 0
+
+		switch {
+		case i >= int(count) && right:
+			return fmt.Errorf("%w: padding bits set", ErrMalformedProof)
+		case i >= int(count):
+			continue
+		case right:
+			proof.Directions = append(proof.Directions, 1)
+		default:
+			proof.Directions = append(proof.Directions, 0)
+		}
+
+		sibling := next(hashSize)
+		if sibling == nil {
+			return fmt.Errorf("%w: truncated", ErrMalformedProof)
+		}
+
+		proof.Siblings = append(proof.Siblings, sibling)
+	}
+
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: trailing data", ErrMalformedProof)
+	}
+
+	if err := proof.validate(); err != nil {
+		return err
+	}
+
+	*p = proof
+
+	return nil
+}
+
+// validate checks that the proof can be encoded: a leaf index within the tree size, a sibling position
+// for every sibling, at most maxProofPathLength siblings and non-empty hashes of the same size of at most
+// maxProofHashSize bytes, which is the size of the named hash algorithm. Returns ErrMalformedProof otherwise.
+func (p Proof) validate() error {
+	if p.TreeSize <= 0 || p.LeafIndex < 0 || p.LeafIndex >= p.TreeSize {
+		return fmt.Errorf("%w: leaf index %d for tree of size %d", ErrMalformedProof, p.LeafIndex, p.TreeSize)
+	}
+
+	if len(p.Siblings) != len(p.Directions) || len(p.Siblings) > maxProofPathLength {
+		return fmt.Errorf("%w: %d siblings with %d directions", ErrMalformedProof, len(p.Siblings), len(p.Directions))
+	}
+
+	for _, d := range p.Directions {
+		if d != 0 && d != 1 {
+			return fmt.Errorf("%w: invalid direction %d", ErrMalformedProof, d)
+		}
+	}
+
+	hashSize := p.hashSize()
+
+	if p.HashAlgorithm != "" {
+		hashFunc, err := HashFuncByName(p.HashAlgorithm)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedProof, err)
+		}
+
+		if hashSize != 0 && hashSize != hashFunc().Size() {
+			return fmt.Errorf("%w: hashes of %d bytes for %s", ErrMalformedProof, hashSize, p.HashAlgorithm)
+		}
+	}
+
+	if hashSize > maxProofHashSize {
+		return fmt.Errorf("%w: hashes of %d bytes", ErrMalformedProof, hashSize)
+	}
+
+	if p.LeafHash != nil && len(p.LeafHash) == 0 {
+		return fmt.Errorf("%w: empty leaf hash", ErrMalformedProof)
+	}
+
+	for _, s := range p.Siblings {
+		if len(s) == 0 || len(s) != hashSize {
+			return fmt.Errorf("%w: siblings of different sizes", ErrMalformedProof)
+		}
+	}
+
+	return nil
+}
+
+// hashSize returns the size of the leaf hash or of the first sibling if there is no leaf hash.
+func (p Proof) hashSize() int {
+	if p.LeafHash != nil {
+		return len(p.LeafHash)
+	}
+
+	if len(p.Siblings) > 0 {
+		return len(p.Siblings[0])
+	}
+
+	return 0
+}
@@ -0,0 +1,173 @@
+package merkletree_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestProofJSON(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[3].payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.ProofByIndex(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"version":1,"algorithm":"sha256","leaf_index":1,"tree_size":` +
+		`4,"leaf_hash":"` + hex.EncodeToString(proof.LeafHash) + `","path":[` +
+		`{"hash":"` + hex.EncodeToString(proof.Siblings[0]) + `","position":"left"},` +
+		`{"hash":"` + hex.EncodeToString(proof.Siblings[1]) + `","position":"right"}]}`
+	if string(data) != expected {
+		t.Errorf("error: expected JSON %s got %s", expected, data)
+	}
+
+	var decoded merkletree.Proof
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(&decoded, proof) {
+		t.Errorf("error: expected decoded proof %v got %v", proof, decoded)
+	}
+
+	verifyProof(t, "json", tree.MerkleRootHash, inputs[3].payloads[1], decoded, true)
+}
+
+func TestProofEncodingRoundTrip(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, payload := range test.payloads {
+				proof, err := tree.ProofByIndex(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				jsonData, err := json.Marshal(proof)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				binaryData, err := proof.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var fromJSON, fromBinary merkletree.Proof
+
+				if err := json.Unmarshal(jsonData, &fromJSON); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := fromBinary.UnmarshalBinary(binaryData); err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(fromJSON, fromBinary) {
+					t.Errorf("[test case: %s] error: expected equal proofs got %v and %v",
+						test.testCaseName, fromJSON, fromBinary)
+				}
+
+				verifyProof(t, test.testCaseName, tree.MerkleRootHash, payload, fromBinary, true, opt)
+			}
+		}
+	}
+}
+
+func TestProofJSONMalformed(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	valid := `{"version":1,"algorithm":"sha256","leaf_index":0,"tree_size":2,"leaf_hash":"` + hash +
+		`","path":[{"hash":"` + hash + `","position":"right"}]}`
+
+	var proof merkletree.Proof
+	if err := json.Unmarshal([]byte(valid), &proof); err != nil {
+		t.Fatal(err)
+	}
+
+	longPath := strings.Repeat(`{"hash":"`+hash+`","position":"left"},`, 65)
+
+	for name, data := range map[string]string{
+		"unknown field":    strings.Replace(valid, `"version"`, `"extra":1,"version"`, 1),
+		"unknown version":  strings.Replace(valid, `"version":1`, `"version":2`, 1),
+		"unknown position": strings.Replace(valid, `"right"`, `"up"`, 1),
+		"invalid hex":      strings.Replace(valid, `"leaf_hash":"ab`, `"leaf_hash":"zz`, 1),
+		"mixed sizes":      strings.Replace(valid, `"leaf_hash":"ab`, `"leaf_hash":"`, 1),
+		"wrong size":       strings.Replace(valid, hash, hash+"abab", -1),
+		"unknown hash":     strings.Replace(valid, `"sha256"`, `"md5"`, 1),
+		"index too large":  strings.Replace(valid, `"leaf_index":0`, `"leaf_index":2`, 1),
+		"path too long":    strings.Replace(valid, `"path":[`, `"path":[`+longPath, 1),
+		"trailing data":    valid + "{}",
+		"trailing brace":   valid + "}",
+		"trailing bracket": valid + "]",
+		"field name case":  strings.Replace(valid, `"leaf_index"`, `"LEAF_INDEX"`, 1),
+		"step name case":   strings.Replace(valid, `"position"`, `"Position"`, 1),
+		"oversized":        strings.Replace(valid, `"leaf_hash":"`, `"leaf_hash":"`+strings.Repeat("ab", 32<<10), 1),
+	} {
+		var decoded merkletree.Proof
+		if err := decoded.UnmarshalJSON([]byte(data)); !errors.Is(err, merkletree.ErrMalformedProof) {
+			t.Errorf("[test case: %s] error: expected ErrMalformedProof got %v", name, err)
+		}
+	}
+}
+
+func TestProofBinaryMalformed(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.ProofByIndex(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for size := 0; size < len(data); size++ {
+		var decoded merkletree.Proof
+		if err := decoded.UnmarshalBinary(data[:size]); !errors.Is(err, merkletree.ErrMalformedProof) {
+			t.Errorf("error: expected ErrMalformedProof for %d bytes got %v", size, err)
+		}
+	}
+
+	// The byte holding the sibling positions follows the version, the algorithm name, the leaf index,
+	// the tree size, the hash size, the leaf hash marker, the leaf hash and the number of siblings.
+	positionsOffset := 2 + len("sha256") + 8 + 8 + 2 + 32 + 1
+
+	padded := append([]byte{}, data...)
+	padded[positionsOffset] |= 0x80
+
+	for name, malformed := range map[string][]byte{
+		"trailing data":   append(append([]byte{}, data...), 0x00),
+		"padding bits":    padded,
+		"unknown version": append([]byte{0x02}, data[1:]...),
+	} {
+		var decoded merkletree.Proof
+		if err := decoded.UnmarshalBinary(malformed); !errors.Is(err, merkletree.ErrMalformedProof) {
+			t.Errorf("[test case: %s] error: expected ErrMalformedProof got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package merkletree_test
+
+import (
+	"errors"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestMerkleTreeGetProof(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatalf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
+		}
+
+		for i, payload := range test.payloads {
+			proof, err := tree.GetProof(payload)
+			if err != nil {
+				t.Fatalf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
+			}
+
+			if proof.LeafIndex != i || proof.TreeSize != len(test.payloads) {
+				t.Errorf("[test case: %s] error: expected leaf %d of %d got leaf %d of %d",
+					test.testCaseName, i, len(test.payloads), proof.LeafIndex, proof.TreeSize)
+			}
+
+			if proof.HashAlgorithm != "sha256" {
+				t.Errorf("[test case: %s] error: expected hash algorithm sha256 got %q",
+					test.testCaseName, proof.HashAlgorithm)
+			}
+		}
+
+		_, err = tree.GetProof(test.invalidPayload)
+		if !errors.Is(err, merkletree.ErrPayloadNotFound) {
+			t.Errorf("[test case: %s] error: expected ErrPayloadNotFound got %v", test.testCaseName, err)
+		}
+	}
+}
+
+func TestMerkleTreeProofByIndex(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, payload := range test.payloads {
+			proof, err := tree.ProofByIndex(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			verifyProof(t, test.testCaseName, tree.MerkleRootHash, payload, *proof, true)
+		}
+
+		for _, i := range []int{-1, len(test.payloads)} {
+			_, err := tree.ProofByIndex(i)
+
+			var rangeErr *merkletree.IndexOutOfRangeError
+			if !errors.As(err, &rangeErr) || rangeErr.Index != i || rangeErr.Size != len(test.payloads) {
+				t.Errorf("[test case: %s] error: expected IndexOutOfRangeError for index %d got %v",
+					test.testCaseName, i, err)
+			}
+		}
+	}
+}
+
+func TestMerkleTreeProofByLeafHash(t *testing.T) {
+	for _, test := range inputs {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, payload := range test.payloads {
+			proof, err := tree.ProofByLeafHash(tree.Leafs[i].Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proof.LeafIndex != i {
+				t.Errorf("[test case: %s] error: expected proof of leaf %d got %d", test.testCaseName, i, proof.LeafIndex)
+			}
+
+			verifyProof(t, test.testCaseName, tree.MerkleRootHash, payload, *proof, true)
+		}
+
+		if _, err := tree.ProofByLeafHash([]byte{123}); !errors.Is(err, merkletree.ErrLeafNotFound) {
+			t.Errorf("[test case: %s] error: expected ErrLeafNotFound got %v", test.testCaseName, err)
+		}
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatalf("[test case: %s] error: unexpected error: %v", test.testCaseName, err)
+			}
+
+			root := append([]byte(nil), tree.MerkleRootHash...)
+
+			for _, payload := range test.payloads {
+				proof, err := tree.GetProof(payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				verifyProof(t, test.testCaseName, root, payload, *proof, true, opt)
+				verifyProof(t, test.testCaseName, root, test.invalidPayload, *proof, false, opt)
+				verifyProof(t, test.testCaseName, []byte{123}, payload, *proof, false, opt)
+
+				if len(proof.Siblings) > 0 {
+					tampered := *proof
+					tampered.Siblings = append([][]byte{{123}}, proof.Siblings[1:]...)
+					verifyProof(t, test.testCaseName, root, payload, tampered, false, opt)
+				}
+
+				if len(test.payloads) > 1 {
+					moved := *proof
+					moved.LeafIndex = (proof.LeafIndex + 1) % proof.TreeSize
+					moved.LeafHash = nil
+					verifyProof(t, test.testCaseName, root, payload, moved, false, opt)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyProofHashAlgorithmMismatch(t *testing.T) {
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.GetProof(test.payloads[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof.HashAlgorithm = "md5"
+
+	if _, err := merkletree.VerifyProof(tree.MerkleRootHash, test.payloads[0], *proof, tree.HashFunc); err == nil {
+		t.Error("error: expected hash algorithm mismatch error")
+	}
+}
+
+func TestVerifyProofInnerNodeForgery(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads[:4], merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The left inner node claimed as the first leaf of a tree of two leaves.
+	forged := merkletree.Proof{
+		LeafIndex:  0,
+		TreeSize:   2,
+		Siblings:   [][]byte{tree.Root.Right.Hash},
+		Directions: []int64{1},
+	}
+
+	_, err = merkletree.VerifyProof(
+		tree.MerkleRootHash, merkletree.HashPayload(tree.Root.Left.Hash), forged, merkletree.SHA256())
+	if !errors.Is(err, merkletree.ErrLeafHashPayload) {
+		t.Errorf("error: expected ErrLeafHashPayload got %v", err)
+	}
+
+	_, err = merkletree.VerifyMultiProof(tree.MerkleRootHash,
+		[]merkletree.Payload{merkletree.HashPayload(tree.Root.Left.Hash)},
+		merkletree.MultiProof{LeafIndices: []int{0}, TreeSize: 2, Hashes: [][]byte{tree.Root.Right.Hash},
+			Flags: []bool{false}}, merkletree.SHA256())
+	if !errors.Is(err, merkletree.ErrLeafHashPayload) {
+		t.Errorf("error: expected ErrLeafHashPayload for multiproof got %v", err)
+	}
+
+	ok, err := merkletree.VerifyLeafHash(tree.MerkleRootHash, tree.Root.Left.Hash, 4, forged, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Error("error: expected forged proof of an inner node to be invalid")
+	}
+
+	proof, err := tree.ProofByIndex(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = merkletree.VerifyLeafHash(tree.MerkleRootHash, tree.Leafs[2].Hash, 4, *proof, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Error("error: expected proof of leaf hash to be valid")
+	}
+}
+
+func verifyProof(
+	t *testing.T, testCaseName string, root []byte, payload merkletree.Payload, proof merkletree.Proof, expected bool,
+	opts ...merkletree.Option) {
+	ok, err := merkletree.VerifyProof(root, payload, proof, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("[test case: %s] error: expected proof of leaf %d to be valid: %t",
+			testCaseName, proof.LeafIndex, expected)
+	}
+}
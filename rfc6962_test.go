@@ -0,0 +1,227 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// rawPayload is a payload holding the raw leaf input of a Certificate Transparency log.
+type rawPayload []byte
+
+func (r rawPayload) CalculateHash() ([]byte, error) {
+	h := sha256.Sum256(r)
+
+	return h[:], nil
+}
+
+func (r rawPayload) Equals(other merkletree.Payload) (bool, error) {
+	o, ok := other.(rawPayload)
+
+	return ok && bytes.Equal(r, o), nil
+}
+
+func (r rawPayload) Encode() ([]byte, error) {
+	return r, nil
+}
+
+// rfc6962Leaves are the leaf inputs of the Certificate Transparency test vectors.
+var rfc6962Leaves = []merkletree.Payload{
+	rawPayload(""),
+	rawPayload("\x00"),
+	rawPayload("\x10"),
+	rawPayload("\x20\x21"),
+	rawPayload("\x30\x31"),
+	rawPayload("\x40\x41\x42\x43"),
+	rawPayload("\x50\x51\x52\x53\x54\x55\x56\x57"),
+	rawPayload("\x60\x61\x62\x63\x64\x65\x66\x67\x68\x69\x6a\x6b\x6c\x6d\x6e\x6f"),
+}
+
+var rfc6962Roots = []string{
+	"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+	"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+	"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+	"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+	"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+	"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+}
+
+var rfc6962InclusionProofs = []struct {
+	leafIndex int
+	treeSize  int
+	path      []string
+}{
+	{
+		leafIndex: 0,
+		treeSize:  8,
+		path: []string{
+			"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+		},
+	},
+	{
+		leafIndex: 5,
+		treeSize:  8,
+		path: []string{
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+			"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		},
+	},
+	{
+		leafIndex: 2,
+		treeSize:  3,
+		path: []string{
+			"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		},
+	},
+	{
+		leafIndex: 1,
+		treeSize:  5,
+		path: []string{
+			"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		},
+	},
+}
+
+var rfc6962ConsistencyProofs = []struct {
+	oldSize int
+	newSize int
+	proof   []string
+}{
+	{
+		oldSize: 1,
+		newSize: 8,
+		proof: []string{
+			"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+		},
+	},
+	{
+		oldSize: 6,
+		newSize: 8,
+		proof: []string{
+			"0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a",
+			"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		},
+	},
+	{
+		oldSize: 2,
+		newSize: 5,
+		proof: []string{
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		},
+	},
+}
+
+func TestRFC6962Roots(t *testing.T) {
+	for size := 1; size <= len(rfc6962Leaves); size++ {
+		tree, err := merkletree.NewTree(rfc6962Leaves[:size], merkletree.SHA256(), merkletree.WithRFC6962())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := hex.EncodeToString(tree.MerkleRootHash); actual != rfc6962Roots[size-1] {
+			t.Errorf("error: expected root of size %d equal to %s got %s", size, rfc6962Roots[size-1], actual)
+		}
+
+		ok, err := tree.VerifyTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Errorf("error: expected tree of size %d to be valid", size)
+		}
+	}
+}
+
+func TestRFC6962InclusionProofs(t *testing.T) {
+	for _, test := range rfc6962InclusionProofs {
+		tree, err := merkletree.NewTree(rfc6962Leaves[:test.treeSize], merkletree.SHA256(), merkletree.WithRFC6962())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := rfc6962Leaves[test.leafIndex]
+
+		proof, err := tree.GetProof(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertHashes(t, proof.Siblings, test.path)
+
+		ok, err := merkletree.VerifyProof(
+			tree.MerkleRootHash, payload, *proof, merkletree.SHA256(), merkletree.WithRFC6962())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Errorf("error: expected proof of leaf %d in tree of size %d to be valid", test.leafIndex, test.treeSize)
+		}
+	}
+}
+
+func TestRFC6962ConsistencyProofs(t *testing.T) {
+	tree, err := merkletree.NewTree(rfc6962Leaves, merkletree.SHA256(), merkletree.WithRFC6962())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range rfc6962ConsistencyProofs {
+		proof, err := tree.ConsistencyProof(test.oldSize, test.newSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertHashes(t, proof, test.proof)
+
+		oldRoot, err := hex.DecodeString(rfc6962Roots[test.oldSize-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		newRoot, err := hex.DecodeString(rfc6962Roots[test.newSize-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := merkletree.VerifyConsistency(
+			oldRoot, newRoot, test.oldSize, test.newSize, proof, merkletree.SHA256(), merkletree.WithRFC6962())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Errorf("error: expected consistency proof between sizes %d and %d to be valid",
+				test.oldSize, test.newSize)
+		}
+	}
+}
+
+func assertHashes(t *testing.T, actual [][]byte, expected []string) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("error: expected %d hashes got %d", len(expected), len(actual))
+	}
+
+	for i := range expected {
+		if hex.EncodeToString(actual[i]) != expected[i] {
+			t.Errorf("error: expected hash %d equal to %s got %x", i, expected[i], actual[i])
+		}
+	}
+}
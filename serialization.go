@@ -0,0 +1,346 @@
+package merkletree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// treeFormatVersion is the version of the binary format written by WriteTo.
+	treeFormatVersion = 1
+	// maxSerializedTreeSize is the largest serialized tree ReadTree accepts.
+	maxSerializedTreeSize int64 = 1 << 32
+)
+
+// treeFormatMagic is the first field of every tree written by WriteTo.
+var treeFormatMagic = []byte("MKTR")
+
+// ErrCorruptTree is returned when a serialized tree is malformed or fails its integrity checks.
+var ErrCorruptTree = errors.New("error: corrupt serialized tree")
+
+// PayloadCodec encodes payloads to bytes and decodes them back, so that they can be saved together with
+// a tree. See WithPayloadCodec.
+type PayloadCodec interface {
+	// EncodePayload returns the bytes representing the payload.
+	EncodePayload(p Payload) ([]byte, error)
+	// DecodePayload returns the payload represented by the bytes.
+	DecodePayload(data []byte) (Payload, error)
+}
+
+// WriteTo writes the tree to w in a versioned binary format, so that it can be loaded with ReadTree without
+// hashing its payloads again. The format holds the name of the hash algorithm and its digest of an empty
+// input, which tells apart algorithms without a name, the options shaping the tree, the hashes of the leaves
+// in order, the payloads if the tree was created WithPayloadCodec, the hashes of all other nodes in the order
+// they are built and a SHA-256 checksum. All fields are written by a CanonicalEncoder. Returns the number of
+// bytes written.
+func (m *BaseTree) WriteTo(w io.Writer) (int64, error) {
+	var e CanonicalEncoder
+
+	e.WriteBytes(treeFormatMagic)
+	e.WriteUint64(treeFormatVersion)
+	e.WriteString(m.hashAlgorithm)
+
+	fingerprint, err := m.HashFunc.Calculate(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	e.WriteBytes(fingerprint)
+	m.config.encode(&e)
+
+	size := m.leafCount()
+	e.WriteUint64(uint64(size))
+
+	for _, n := range m.Leafs[:size] {
+		e.WriteBytes(n.Hash)
+
+		if m.config.payloadCodec == nil || n.hashOnly() {
+			e.WriteUint64(0)
+			continue
+		}
+
+		data, err := m.config.payloadCodec.EncodePayload(n.Payload)
+		if err != nil {
+			return 0, err
+		}
+
+		e.WriteUint64(1)
+		e.WriteBytes(data)
+	}
+
+	hashes := m.innerNodeHashes()
+	e.WriteUint64(uint64(len(hashes)))
+
+	for _, h := range hashes {
+		e.WriteBytes(h)
+	}
+
+	data, err := e.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	checksum := sha256.Sum256(data)
+
+	n, err := w.Write(append(data, checksum[:]...))
+
+	return int64(n), err
+}
+
+// ReadTree reads a tree written by WriteTo. The hash function has to be the one the tree was created with.
+// The options shaping the tree are read from the data, other options, such as WithPayloadCodec and
+// WithSortKey, have to be passed again. The node hashes are taken as they are, relying on the checksum of the
+// data, so only the decoded payloads are hashed, and VerifyTree checks all of them. Returns ErrCorruptTree if
+// the data is malformed, its checksum does not match or a decoded payload does not match its leaf hash.
+func ReadTree(r io.Reader, hashFunc HashFunc, opts ...Option) (*MerkleTree, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSerializedTreeSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < sha256.Size || int64(len(data)) > maxSerializedTreeSize {
+		return nil, ErrCorruptTree
+	}
+
+	body, checksum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if sum := sha256.Sum256(body); !bytes.Equal(sum[:], checksum) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptTree)
+	}
+
+	config, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewCanonicalDecoder(body)
+
+	if !bytes.Equal(d.ReadBytes(), treeFormatMagic) {
+		return nil, fmt.Errorf("%w: not a serialized tree", ErrCorruptTree)
+	}
+
+	if version := d.ReadUint64(); version != treeFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrCorruptTree, version)
+	}
+
+	fingerprint, err := hashFunc.Calculate(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hashAlgorithm := hashFunc.Name()
+
+	if name := d.ReadString(); name != hashAlgorithm || !bytes.Equal(d.ReadBytes(), fingerprint) {
+		return nil, fmt.Errorf(
+			"error: tree uses hash algorithm %q, reading with a different algorithm %q", name, hashAlgorithm)
+	}
+
+	if err := config.decode(d); err != nil {
+		return nil, err
+	}
+
+	t := &MerkleTree{
+		BaseTree: BaseTree{
+			HashFunc:      hashFunc,
+			config:        config,
+			hashAlgorithm: hashAlgorithm,
+		},
+	}
+
+	leafNodes, err := t.decodeLeafNodes(d)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeHashes := &storedNodeHashes{d: d, hashSize: hashFunc().Size(), count: d.ReadUint64()}
+
+	if err := t.buildFromLeafNodes(leafNodes, nodeHashes.next); err != nil {
+		return nil, err
+	}
+
+	if nodeHashes.read != nodeHashes.count {
+		return nil, fmt.Errorf("%w: %d node hashes for a tree with %d", ErrCorruptTree, nodeHashes.count,
+			nodeHashes.read)
+	}
+
+	if d.Remaining() != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrCorruptTree)
+	}
+
+	return t, nil
+}
+
+// encode writes the options shaping a tree.
+func (c treeConfig) encode(e *CanonicalEncoder) {
+	e.WriteUint64(uint64(c.oddNodeStrategy))
+
+	if c.leafPrefix == nil {
+		e.WriteUint64(0)
+	} else {
+		e.WriteUint64(1)
+		e.WriteBytes(c.leafPrefix)
+		e.WriteBytes(c.nodePrefix)
+	}
+
+	switch {
+	case !c.sorted:
+		e.WriteUint64(0)
+	case c.sortKey == nil:
+		e.WriteUint64(1)
+	default:
+		e.WriteUint64(2)
+	}
+}
+
+// decode reads the options shaping a tree written by encode and applies them. A tree sorted by a custom
+// key requires the key function to be set already.
+func (c *treeConfig) decode(d *CanonicalDecoder) error {
+	strategy := d.ReadUint64()
+	if strategy > uint64(OddNodeZeroPad) {
+		return fmt.Errorf("%w: unknown odd node strategy %d", ErrCorruptTree, strategy)
+	}
+
+	c.oddNodeStrategy = OddNodeStrategy(strategy)
+	c.leafPrefix, c.nodePrefix = nil, nil
+
+	if d.ReadUint64() != 0 {
+		c.leafPrefix = d.ReadBytes()
+		c.nodePrefix = d.ReadBytes()
+	}
+
+	switch d.ReadUint64() {
+	case 0:
+		c.sorted, c.sortKey = false, nil
+	case 1:
+		c.sorted, c.sortKey = true, nil
+	default:
+		if c.sortKey == nil {
+			return errors.New("error: tree sorted by a custom key requires WithSortKey")
+		}
+
+		c.sorted = true
+	}
+
+	if err := d.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+
+	if err := c.validatePrefixes(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+
+	return nil
+}
+
+// decodeLeafNodes reads the leaves of a tree written by WriteTo. Payloads are decoded with the payload
+// codec of the tree, and checked against their leaf hashes, or skipped if it has none.
+func (m *BaseTree) decodeLeafNodes(d *CanonicalDecoder) ([]*Node, error) {
+	size := d.ReadUint64()
+
+	// Every leaf takes at least the length prefix of its hash and its payload marker.
+	if size == 0 || size > uint64(d.Remaining()/12) {
+		return nil, fmt.Errorf("%w: invalid number of leaves %d", ErrCorruptTree, size)
+	}
+
+	hashSize := m.HashFunc().Size()
+	leafNodes := make([]*Node, 0, size)
+
+	for i := uint64(0); i < size; i++ {
+		n := &Node{
+			Hash:   d.ReadBytes(),
+			isLeaf: true,
+			Tree:   m,
+		}
+
+		if d.Err() == nil && len(n.Hash) != hashSize {
+			return nil, fmt.Errorf("%w: leaf hash of %d bytes", ErrCorruptTree, len(n.Hash))
+		}
+
+		if d.ReadUint64() != 0 {
+			data := d.ReadBytes()
+
+			if m.config.payloadCodec != nil && d.Err() == nil {
+				p, err := m.config.payloadCodec.DecodePayload(data)
+				if err != nil {
+					return nil, err
+				}
+
+				hash, err := m.hashLeaf(p)
+				if err != nil {
+					return nil, err
+				}
+
+				if !bytes.Equal(hash, n.Hash) {
+					return nil, fmt.Errorf("%w: payload of leaf %d does not match its hash", ErrCorruptTree, i)
+				}
+
+				n.Payload = p
+			}
+		}
+
+		leafNodes = append(leafNodes, n)
+	}
+
+	if err := d.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+
+	return leafNodes, nil
+}
+
+// innerNodeHashes returns the hashes of the nodes of the tree that are neither leaves nor padding, level by
+// level from left to right, which is the order they are built in. A promoted node is carried to the level
+// above.
+func (m *BaseTree) innerNodeHashes() [][]byte {
+	var hashes [][]byte
+
+	for level := m.Leafs; len(level) > 1; {
+		var parents []*Node
+
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) && m.config.oddNodeStrategy == OddNodePromote {
+				parents = append(parents, level[i])
+				continue
+			}
+
+			parent := level[i].Parent
+			hashes = append(hashes, parent.Hash)
+			parents = append(parents, parent)
+		}
+
+		level = parents
+	}
+
+	return hashes
+}
+
+// storedNodeHashes hands out the node hashes written by WriteTo while the tree is built again.
+type storedNodeHashes struct {
+	d        *CanonicalDecoder
+	hashSize int
+	// count is the number of stored hashes and read the number of hashes handed out.
+	count uint64
+	read  uint64
+}
+
+// next returns the next stored hash as the hash of the node built from the given children.
+func (s *storedNodeHashes) next(_, _ *Node) ([]byte, error) {
+	if s.read == s.count {
+		return nil, fmt.Errorf("%w: missing node hashes", ErrCorruptTree)
+	}
+
+	s.read++
+
+	hash := s.d.ReadBytes()
+	if err := s.d.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+
+	if len(hash) != s.hashSize {
+		return nil, fmt.Errorf("%w: node hash of %d bytes", ErrCorruptTree, len(hash))
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,238 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+// paymentCodec encodes payments with their canonical encoding.
+type paymentCodec struct{}
+
+func (paymentCodec) EncodePayload(p merkletree.Payload) ([]byte, error) {
+	return p.(merkletree.PaymentTransactionPayload).Encode()
+}
+
+func (paymentCodec) DecodePayload(data []byte) (merkletree.Payload, error) {
+	d := merkletree.NewCanonicalDecoder(data)
+	p := merkletree.PaymentTransactionPayload{
+		SenderAddress:   d.ReadString(),
+		ReceiverAddress: d.ReadString(),
+		Amount:          d.ReadInt64(),
+	}
+
+	if d.Remaining() != 0 {
+		return nil, errors.New("error: trailing payment data")
+	}
+
+	return p, d.Err()
+}
+
+// amountCodec decodes payments like paymentCodec but doubles their amount.
+type amountCodec struct {
+	paymentCodec
+}
+
+func (c amountCodec) DecodePayload(data []byte) (merkletree.Payload, error) {
+	p, err := c.paymentCodec.DecodePayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := p.(merkletree.PaymentTransactionPayload)
+	payment.Amount *= 2
+
+	return payment, nil
+}
+
+func writeTree(t *testing.T, tree *merkletree.MerkleTree) []byte {
+	var buf bytes.Buffer
+
+	n, err := tree.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(buf.Len()) {
+		t.Errorf("error: expected %d bytes written got %d", buf.Len(), n)
+	}
+
+	return buf.Bytes()
+}
+
+func TestReadTree(t *testing.T) {
+	optionSets := map[string][]merkletree.Option{
+		"default":   nil,
+		"rfc 6962":  {merkletree.WithRFC6962()},
+		"zero pad":  {merkletree.WithOddNodeStrategy(merkletree.OddNodeZeroPad)},
+		"legacy":    {merkletree.WithLegacyHashing()},
+		"sorted":    {merkletree.WithSortedLeaves()},
+		"sort key":  {merkletree.WithSortKey(senderSortKey)},
+		"separated": {merkletree.WithDomainSeparation([]byte("leaf"), []byte("node"))},
+	}
+
+	for name, opts := range optionSets {
+		for _, test := range inputs {
+			opts := append([]merkletree.Option{merkletree.WithPayloadCodec(paymentCodec{})}, opts...)
+
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			read, err := merkletree.ReadTree(bytes.NewReader(writeTree(t, tree)), merkletree.SHA256(),
+				merkletree.WithPayloadCodec(paymentCodec{}), merkletree.WithSortKey(senderSortKey))
+			if err != nil {
+				t.Fatalf("[test case: %s, %s] %v", test.testCaseName, name, err)
+			}
+
+			if !bytes.Equal(read.MerkleRootHash, tree.MerkleRootHash) {
+				t.Errorf("[test case: %s, %s] error: expected hash equal to %x got %x",
+					test.testCaseName, name, tree.MerkleRootHash, read.MerkleRootHash)
+			}
+
+			if !reflect.DeepEqual(read.Items(), tree.Items()) {
+				t.Errorf("[test case: %s, %s] error: expected items %v got %v",
+					test.testCaseName, name, tree.Items(), read.Items())
+			}
+
+			ok, err := read.VerifyTree()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Errorf("[test case: %s, %s] error: expected read tree to be valid", test.testCaseName, name)
+			}
+
+			proof, err := read.GetProof(test.payloads[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err = merkletree.VerifyProof(tree.MerkleRootHash, test.payloads[0], *proof, merkletree.SHA256(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !ok {
+				t.Errorf("[test case: %s, %s] error: expected proof from read tree to be valid", test.testCaseName, name)
+			}
+		}
+	}
+}
+
+func TestReadTreeWithoutPayloads(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := merkletree.ReadTree(bytes.NewReader(writeTree(t, tree)), merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(read.MerkleRootHash, tree.MerkleRootHash) {
+		t.Errorf("error: expected hash equal to %x got %x", tree.MerkleRootHash, read.MerkleRootHash)
+	}
+
+	if read.Leafs[0].Payload != nil {
+		t.Error("error: expected tree read without payload codec to hold no payloads")
+	}
+
+	proof, err := read.ProofByIndex(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyProof(t, "hashes only", tree.MerkleRootHash, inputs[0].payloads[2], *proof, true)
+}
+
+func TestReadTreeCorrupt(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := writeTree(t, tree)
+
+	for i := 0; i < len(data); i++ {
+		corrupt := append([]byte{}, data...)
+		corrupt[i] ^= 0x01
+
+		if _, err := merkletree.ReadTree(bytes.NewReader(corrupt), merkletree.SHA256()); !errors.Is(
+			err, merkletree.ErrCorruptTree) {
+			t.Fatalf("error: expected ErrCorruptTree for flipped bit in byte %d got %v", i, err)
+		}
+	}
+
+	for _, size := range []int{0, 1, len(data) - 1} {
+		if _, err := merkletree.ReadTree(bytes.NewReader(data[:size]), merkletree.SHA256()); !errors.Is(
+			err, merkletree.ErrCorruptTree) {
+			t.Errorf("error: expected ErrCorruptTree for %d bytes got %v", size, err)
+		}
+	}
+
+	// A wrong root hash with a matching checksum, as written by a faulty writer, is only found by VerifyTree.
+	forged := append([]byte{}, data[:len(data)-sha256.Size]...)
+	forged[len(forged)-1] ^= 0x01
+	checksum := sha256.Sum256(forged)
+
+	read, err := merkletree.ReadTree(bytes.NewReader(append(forged, checksum[:]...)), merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := read.VerifyTree(); err != nil || ok {
+		t.Errorf("error: expected tree with wrong root hash to be invalid got %v, %v", ok, err)
+	}
+
+	if _, err := merkletree.ReadTree(bytes.NewReader(data), merkletree.SHA512()); err == nil {
+		t.Error("error: expected error for tree read with another hash algorithm")
+	}
+}
+
+func TestReadTreePayloadMismatch(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.SHA256(), merkletree.WithPayloadCodec(paymentCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := writeTree(t, tree)
+
+	// A codec decoding to another payload than the one the leaf hash was calculated from.
+	_, err = merkletree.ReadTree(bytes.NewReader(data), merkletree.SHA256(), merkletree.WithPayloadCodec(amountCodec{}))
+	if !errors.Is(err, merkletree.ErrCorruptTree) {
+		t.Errorf("error: expected ErrCorruptTree for payload not matching its leaf hash got %v", err)
+	}
+}
+
+func TestReadTreeUnidentifiedHashFunc(t *testing.T) {
+	tree, err := merkletree.NewTree(inputs[0].payloads, merkletree.HashFunc(sha1.New))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := writeTree(t, tree)
+
+	read, err := merkletree.ReadTree(bytes.NewReader(data), sha1.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(read.MerkleRootHash, tree.MerkleRootHash) {
+		t.Errorf("error: expected root %x got %x", tree.MerkleRootHash, read.MerkleRootHash)
+	}
+
+	// Neither algorithm has a name, they are told apart by their digests.
+	if _, err := merkletree.ReadTree(bytes.NewReader(data), md5.New); err == nil {
+		t.Error("error: expected error for tree read with another unknown hash algorithm")
+	}
+}
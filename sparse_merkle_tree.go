@@ -0,0 +1,320 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrKeyNotFound is returned when a key is looked up that is not stored in a SparseMerkleTree.
+var ErrKeyNotFound = errors.New("error: key not found in tree")
+
+// SparseMerkleTree represents an authenticated key/value map. Every key is stored at the leaf addressed by the
+// bits of its hash, in a tree with one level per bit of the hash, e.g. 256 levels for SHA-256. All the leaves
+// without a value hold the same empty hash, so the hashes of empty subtrees are precomputed and only the nodes
+// on the paths to the stored keys are kept. Proofs can show both that a key has a value and that it has none.
+type SparseMerkleTree struct {
+	MerkleRootHash []byte
+	HashFunc       HashFunc
+	config         treeConfig
+	// hashAlgorithm is the name of the hash algorithm, resolved once when the tree is created.
+	hashAlgorithm string
+	// depth is the number of levels above the leaves, which is the number of bits of a hash.
+	depth int
+	// defaultHashes holds the hash of an empty subtree for each height, starting at the leaves.
+	defaultHashes [][]byte
+	// nodes holds the hashes of the non-empty nodes keyed by their height and position.
+	nodes map[string][]byte
+	// values holds the stored values keyed by the hash of their key.
+	values map[string][]byte
+}
+
+// SparseProof proves that a key of a SparseMerkleTree has a given value or that it has no value. Only the
+// sibling hashes that differ from the hash of an empty subtree are included.
+type SparseProof struct {
+	// Bitmap has the bit of a height set, starting from the least significant bit of the first byte, when
+	// the sibling at that height is included in Siblings.
+	Bitmap []byte
+	// Siblings are the included sibling hashes on the path from the leaf to the root.
+	Siblings [][]byte
+	// HashAlgorithm is the name of the hash algorithm of the tree or empty if it is not a known one.
+	HashAlgorithm string
+}
+
+// NewSparseMerkleTree creates an empty SparseMerkleTree using the given type of hash function. Of the options,
+// only the ones configuring the hashing of leaves and nodes apply. The same options have to be passed to
+// VerifySparseProof.
+func NewSparseMerkleTree(hashFunc HashFunc, opts ...Option) (*SparseMerkleTree, error) {
+	config, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultHashes, err := sparseDefaultHashes(hashFunc, config)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := len(defaultHashes) - 1
+
+	return &SparseMerkleTree{
+		MerkleRootHash: defaultHashes[depth],
+		HashFunc:       hashFunc,
+		config:         config,
+		hashAlgorithm:  hashFunc.Name(),
+		depth:          depth,
+		defaultHashes:  defaultHashes,
+		nodes:          make(map[string][]byte),
+		values:         make(map[string][]byte),
+	}, nil
+}
+
+// Get returns the value stored for the key. Returns ErrKeyNotFound if the key has no value.
+func (s *SparseMerkleTree) Get(key []byte) ([]byte, error) {
+	path, err := s.HashFunc.Calculate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := s.values[string(path)]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return value, nil
+}
+
+// Set stores the value for the key, replacing any previous value. Only the hashes on the path from the
+// leaf of the key to the root are recomputed.
+func (s *SparseMerkleTree) Set(key, value []byte) error {
+	path, err := s.HashFunc.Calculate(key)
+	if err != nil {
+		return err
+	}
+
+	value = append([]byte{}, value...)
+
+	leafHash, err := s.config.hashSparseLeaf(s.HashFunc, path, value)
+	if err != nil {
+		return err
+	}
+
+	if err := s.updatePath(path, leafHash); err != nil {
+		return err
+	}
+
+	s.values[string(path)] = value
+
+	return nil
+}
+
+// Delete removes the value of the key. Deleting a key without a value does nothing.
+func (s *SparseMerkleTree) Delete(key []byte) error {
+	path, err := s.HashFunc.Calculate(key)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.values[string(path)]; !ok {
+		return nil
+	}
+
+	if err := s.updatePath(path, s.defaultHashes[0]); err != nil {
+		return err
+	}
+
+	delete(s.values, string(path))
+
+	return nil
+}
+
+// Prove builds a SparseProof for the key. If the key has a value the proof shows it is the value of the
+// key, otherwise the proof shows the key has no value.
+func (s *SparseMerkleTree) Prove(key []byte) (*SparseProof, error) {
+	path, err := s.HashFunc.Calculate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &SparseProof{
+		Bitmap:        make([]byte, (s.depth+7)/8),
+		HashAlgorithm: s.hashAlgorithm,
+	}
+
+	for height := 0; height < s.depth; height++ {
+		sibling := s.node(height, sparseSiblingPath(path, height, s.depth))
+		if bytes.Equal(sibling, s.defaultHashes[height]) {
+			continue
+		}
+
+		proof.Bitmap[height/8] |= 1 << (height % 8)
+		proof.Siblings = append(proof.Siblings, sibling)
+	}
+
+	return proof, nil
+}
+
+// updatePath stores the hash of the leaf at the given path and recomputes the hashes of its ancestors.
+func (s *SparseMerkleTree) updatePath(path, leafHash []byte) error {
+	hash := leafHash
+
+	for height := 0; height < s.depth; height++ {
+		s.setNode(height, path, hash)
+
+		sibling := s.node(height, sparseSiblingPath(path, height, s.depth))
+
+		var err error
+
+		if sparsePathBit(path, s.depth-1-height) == 0 {
+			hash, err = s.config.hashChildren(s.HashFunc, hash, sibling)
+		} else {
+			hash, err = s.config.hashChildren(s.HashFunc, sibling, hash)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	s.MerkleRootHash = hash
+
+	return nil
+}
+
+// node returns the hash of the node at the given height on the given path.
+func (s *SparseMerkleTree) node(height int, path []byte) []byte {
+	if hash, ok := s.nodes[sparseNodeKey(height, path, s.depth)]; ok {
+		return hash
+	}
+
+	return s.defaultHashes[height]
+}
+
+// setNode stores the hash of the node at the given height on the given path. Hashes of empty subtrees
+// are not stored.
+func (s *SparseMerkleTree) setNode(height int, path, hash []byte) {
+	key := sparseNodeKey(height, path, s.depth)
+
+	if bytes.Equal(hash, s.defaultHashes[height]) {
+		delete(s.nodes, key)
+		return
+	}
+
+	s.nodes[key] = hash
+}
+
+// VerifySparseProof checks the proof for the key against the merkle root of a SparseMerkleTree. A nil value
+// checks that the key has no value, any other value that it is the value of the key. The options have to
+// match the ones the tree was created with. Returns true if valid and false otherwise.
+func VerifySparseProof(
+	root, key, value []byte, proof SparseProof, hashFunc HashFunc, opts ...Option) (bool, error) {
+	if err := checkHashAlgorithm(proof.HashAlgorithm, hashFunc); err != nil {
+		return false, err
+	}
+
+	c, err := newTreeConfig(opts)
+	if err != nil {
+		return false, err
+	}
+
+	defaultHashes, err := sparseDefaultHashes(hashFunc, c)
+	if err != nil {
+		return false, err
+	}
+
+	depth := len(defaultHashes) - 1
+	if len(proof.Bitmap) != (depth+7)/8 {
+		return false, nil
+	}
+
+	path, err := hashFunc.Calculate(key)
+	if err != nil {
+		return false, err
+	}
+
+	hash := defaultHashes[0]
+
+	if value != nil {
+		if hash, err = c.hashSparseLeaf(hashFunc, path, value); err != nil {
+			return false, err
+		}
+	}
+
+	siblings := proof.Siblings
+
+	for height := 0; height < depth; height++ {
+		sibling := defaultHashes[height]
+
+		if proof.Bitmap[height/8]&(1<<(height%8)) != 0 {
+			if len(siblings) == 0 {
+				return false, nil
+			}
+
+			sibling, siblings = siblings[0], siblings[1:]
+		}
+
+		if sparsePathBit(path, depth-1-height) == 0 {
+			hash, err = c.hashChildren(hashFunc, hash, sibling)
+		} else {
+			hash, err = c.hashChildren(hashFunc, sibling, hash)
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return len(siblings) == 0 && bytes.Equal(hash, root), nil
+}
+
+// hashSparseLeaf calculates the hash of the leaf of a SparseMerkleTree holding the value at the given path.
+// The path is part of the hash, so a leaf cannot be presented as the leaf of another key.
+func (c treeConfig) hashSparseLeaf(hashFunc HashFunc, path, value []byte) ([]byte, error) {
+	return hashFunc.sum(c.leafPrefix, path, value)
+}
+
+// sparseDefaultHashes returns the hash of an empty subtree of a SparseMerkleTree for each height, from an
+// empty leaf up to the empty tree.
+func sparseDefaultHashes(hashFunc HashFunc, c treeConfig) ([][]byte, error) {
+	depth := hashFunc().Size() * 8
+	defaultHashes := make([][]byte, depth+1)
+	defaultHashes[0] = make([]byte, hashFunc().Size())
+
+	for height := 1; height <= depth; height++ {
+		hash, err := c.hashChildren(hashFunc, defaultHashes[height-1], defaultHashes[height-1])
+		if err != nil {
+			return nil, err
+		}
+
+		defaultHashes[height] = hash
+	}
+
+	return defaultHashes, nil
+}
+
+// sparsePathBit returns the bit of the path at the given position, counted from the most significant bit.
+func sparsePathBit(path []byte, i int) byte {
+	return (path[i/8] >> (7 - i%8)) & 1
+}
+
+// sparseSiblingPath returns a path leading to the sibling of the node at the given height on the path.
+func sparseSiblingPath(path []byte, height, depth int) []byte {
+	i := depth - 1 - height
+	sibling := append([]byte{}, path...)
+	sibling[i/8] ^= 1 << (7 - i%8)
+
+	return sibling
+}
+
+// sparseNodeKey returns the key under which the node at the given height on the path is stored. It is made
+// of the height and the bits of the path leading from the root to the node.
+func sparseNodeKey(height int, path []byte, depth int) string {
+	key := make([]byte, 2+len(path))
+	key[0], key[1] = byte(height>>8), byte(height)
+	copy(key[2:], path)
+
+	for i := depth - height; i < depth; i++ {
+		key[2+i/8] &^= 1 << (7 - i%8)
+	}
+
+	return string(key)
+}
@@ -0,0 +1,221 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+var sparseEntries = map[string]string{
+	"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2":         "100",
+	"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy":         "25",
+	"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq": "7",
+	"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa":         "50",
+}
+
+func newSparseTree(t *testing.T, opts ...merkletree.Option) *merkletree.SparseMerkleTree {
+	t.Helper()
+
+	tree, err := merkletree.NewSparseMerkleTree(merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range sparseEntries {
+		if err := tree.Set([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tree
+}
+
+func TestSparseMerkleTreeGet(t *testing.T) {
+	tree := newSparseTree(t)
+
+	for key, value := range sparseEntries {
+		got, err := tree.Get([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != value {
+			t.Errorf("[key: %s] error: expected value %s, got %s", key, value, got)
+		}
+	}
+
+	if _, err := tree.Get([]byte("unknown")); !errors.Is(err, merkletree.ErrKeyNotFound) {
+		t.Errorf("error: expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestSparseMerkleTreeSetIsOrderIndependent(t *testing.T) {
+	expected := newSparseTree(t)
+
+	tree, err := merkletree.NewSparseMerkleTree(merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq",
+		"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+	} {
+		if err := tree.Set([]byte(key), []byte("0")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for key, value := range sparseEntries {
+		if err := tree.Set([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, expected.MerkleRootHash) {
+		t.Errorf("error: expected root %x, got %x", expected.MerkleRootHash, tree.MerkleRootHash)
+	}
+}
+
+func TestSparseMerkleTreeDelete(t *testing.T) {
+	empty, err := merkletree.NewSparseMerkleTree(merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := newSparseTree(t)
+
+	for key := range sparseEntries {
+		if err := tree.Delete([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tree.Get([]byte(key)); !errors.Is(err, merkletree.ErrKeyNotFound) {
+			t.Errorf("[key: %s] error: expected ErrKeyNotFound after delete, got %v", key, err)
+		}
+
+		if err := tree.Delete([]byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, empty.MerkleRootHash) {
+		t.Errorf("error: expected root of empty tree %x, got %x", empty.MerkleRootHash, tree.MerkleRootHash)
+	}
+}
+
+func TestSparseMerkleTreeInclusionProof(t *testing.T) {
+	for _, opt := range []merkletree.Option{
+		merkletree.WithDomainSeparation([]byte{0x02}, []byte{0x03}),
+		merkletree.WithLegacyHashing(),
+	} {
+		tree := newSparseTree(t, opt)
+
+		for key, value := range sparseEntries {
+			proof, err := tree.Prove([]byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(proof.Siblings) >= len(sparseEntries) {
+				t.Errorf("[key: %s] error: expected compressed proof, got %d siblings", key, len(proof.Siblings))
+			}
+
+			verifySparseProof(t, key, tree.MerkleRootHash, []byte(value), proof, true, opt)
+			verifySparseProof(t, key, tree.MerkleRootHash, []byte(value+"0"), proof, false, opt)
+			verifySparseProof(t, key, tree.MerkleRootHash, nil, proof, false, opt)
+		}
+	}
+}
+
+func TestSparseMerkleTreeExclusionProof(t *testing.T) {
+	tree := newSparseTree(t)
+
+	for i := 0; i < 8; i++ {
+		key := fmt.Sprintf("unknown-%d", i)
+
+		proof, err := tree.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verifySparseProof(t, key, tree.MerkleRootHash, nil, proof, true)
+		verifySparseProof(t, key, tree.MerkleRootHash, []byte{}, proof, false)
+	}
+
+	for key := range sparseEntries {
+		proof, err := tree.Prove([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verifySparseProof(t, key, tree.MerkleRootHash, nil, proof, false)
+	}
+}
+
+func TestSparseMerkleTreeEmptyValue(t *testing.T) {
+	tree := newSparseTree(t)
+	key := []byte("empty")
+
+	if err := tree.Set(key, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := tree.Prove(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifySparseProof(t, string(key), tree.MerkleRootHash, []byte{}, proof, true)
+	verifySparseProof(t, string(key), tree.MerkleRootHash, nil, proof, false)
+}
+
+func TestVerifySparseProofTampered(t *testing.T) {
+	tree := newSparseTree(t)
+	key := "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+
+	proof, err := tree.Prove([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withoutSibling := *proof
+	withoutSibling.Siblings = proof.Siblings[1:]
+	verifySparseProof(t, key, tree.MerkleRootHash, []byte("100"), &withoutSibling, false)
+
+	extraSibling := *proof
+	extraSibling.Siblings = append(append([][]byte{}, proof.Siblings...), proof.Siblings[0])
+	verifySparseProof(t, key, tree.MerkleRootHash, []byte("100"), &extraSibling, false)
+
+	shortBitmap := *proof
+	shortBitmap.Bitmap = proof.Bitmap[1:]
+	verifySparseProof(t, key, tree.MerkleRootHash, []byte("100"), &shortBitmap, false)
+
+	otherAlgorithm := *proof
+	otherAlgorithm.HashAlgorithm = "sha512"
+
+	_, err = merkletree.VerifySparseProof(
+		tree.MerkleRootHash, []byte(key), []byte("100"), otherAlgorithm, merkletree.SHA256())
+	if err == nil {
+		t.Error("error: expected error for mismatched hash algorithm")
+	}
+}
+
+func verifySparseProof(t *testing.T, key string, root, value []byte, proof *merkletree.SparseProof,
+	expected bool, opts ...merkletree.Option) {
+	t.Helper()
+
+	ok, err := merkletree.VerifySparseProof(root, []byte(key), value, *proof, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok != expected {
+		t.Errorf("[key: %s] error: expected proof for value %q to be %t", key, value, expected)
+	}
+}
@@ -0,0 +1,246 @@
+package merkletree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StoredTree is a merkle tree whose node hashes are kept in a NodeStore instead of in memory, so it can hold
+// more leaves than fit in memory. Only the most recently used hashes are kept in memory, see WithCacheSize.
+// The payloads are not kept at all. Its roots and proofs are the ones of a MerkleTree with the same leaves
+// and options, so the proofs are verified with VerifyProof.
+type StoredTree struct {
+	MerkleRootHash []byte
+	HashFunc       HashFunc
+	config         treeConfig
+	nodes          *nodeCache
+	// hashAlgorithm is the name of the hash algorithm, resolved once when the tree is opened.
+	hashAlgorithm string
+	// size is the number of leaves in the tree.
+	size int
+}
+
+// NewStoredTree creates an empty StoredTree keeping its node hashes in the given store, which has to hold
+// no tree, using the given type of hash function. Sorted trees are not supported.
+func NewStoredTree(store NodeStore, hashFunc HashFunc, opts ...Option) (*StoredTree, error) {
+	t, err := OpenStoredTree(store, hashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.size != 0 {
+		return nil, errors.New("error: node store already holds a tree")
+	}
+
+	return t, nil
+}
+
+// OpenStoredTree opens the StoredTree whose node hashes and number of leaves were put in the given store
+// by an earlier StoredTree. The options have to match the ones the tree was created with.
+func OpenStoredTree(store NodeStore, hashFunc HashFunc, opts ...Option) (*StoredTree, error) {
+	config, err := newTreeConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.sorted {
+		return nil, errors.New("error: stored trees cannot be sorted")
+	}
+
+	size, err := store.LeafCount()
+	if err != nil {
+		return nil, err
+	}
+
+	if size < 0 {
+		return nil, fmt.Errorf("error: invalid tree size %d", size)
+	}
+
+	t := &StoredTree{
+		HashFunc:      hashFunc,
+		config:        config,
+		nodes:         newNodeCache(store, config.cacheSize),
+		hashAlgorithm: hashFunc.Name(),
+		size:          size,
+	}
+
+	if size > 0 {
+		root, err := t.nodes.Get(config.treeHeight(size), 0)
+		if err != nil {
+			return nil, err
+		}
+
+		t.MerkleRootHash = root
+	}
+
+	return t, nil
+}
+
+// Size returns the number of leaves in the tree.
+func (t *StoredTree) Size() int {
+	return t.size
+}
+
+// Append adds the payloads as new leaves at the end of the tree and stores the new number of leaves. Only
+// the nodes above the new leaves are recomputed.
+func (t *StoredTree) Append(pp ...Payload) error {
+	if len(pp) == 0 {
+		return nil
+	}
+
+	for i, p := range pp {
+		hash, err := t.config.hashLeaf(t.HashFunc, p)
+		if err != nil {
+			return err
+		}
+
+		if err := t.nodes.Put(0, t.size+i, hash); err != nil {
+			return err
+		}
+	}
+
+	from := t.size
+	t.size += len(pp)
+
+	if err := t.recompute(from, t.size-1); err != nil {
+		return err
+	}
+
+	return t.nodes.SetLeafCount(t.size)
+}
+
+// UpdateLeaf replaces the payload of the leaf at the given position and recomputes the nodes on the path
+// from the leaf to the root. Returns an *IndexOutOfRangeError if there is no leaf at the position.
+func (t *StoredTree) UpdateLeaf(i int, p Payload) error {
+	if i < 0 || i >= t.size {
+		return &IndexOutOfRangeError{Index: i, Size: t.size}
+	}
+
+	hash, err := t.config.hashLeaf(t.HashFunc, p)
+	if err != nil {
+		return err
+	}
+
+	if err := t.nodes.Put(0, i, hash); err != nil {
+		return err
+	}
+
+	return t.recompute(i, i)
+}
+
+// LeafHash returns the hash of the leaf at the given position. Returns an *IndexOutOfRangeError if there
+// is no leaf at the position.
+func (t *StoredTree) LeafHash(i int) ([]byte, error) {
+	if i < 0 || i >= t.size {
+		return nil, &IndexOutOfRangeError{Index: i, Size: t.size}
+	}
+
+	return t.nodes.Get(0, i)
+}
+
+// ProofByIndex builds a Proof for the leaf at the given position, reading only the hashes on its path from
+// the store. Returns an *IndexOutOfRangeError if there is no leaf at the position.
+func (t *StoredTree) ProofByIndex(i int) (*Proof, error) {
+	steps, err := pathSteps(i, t.size, t.config)
+	if err != nil {
+		return nil, err
+	}
+
+	leafHash, err := t.nodes.Get(0, i)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &Proof{
+		LeafHash:      leafHash,
+		LeafIndex:     i,
+		TreeSize:      t.size,
+		HashAlgorithm: t.hashAlgorithm,
+	}
+
+	for _, step := range steps {
+		index := i >> step.level
+
+		var sibling []byte
+
+		switch {
+		case step.duplicate:
+			sibling, err = t.nodes.Get(step.level, index)
+		case step.padding:
+			sibling, err = t.config.zeroHash(t.HashFunc, step.level)
+		default:
+			sibling, err = t.nodes.Get(step.level, index^1)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		proof.Siblings = append(proof.Siblings, sibling)
+		proof.Directions = append(proof.Directions, step.direction)
+	}
+
+	return proof, nil
+}
+
+// recompute recalculates the nodes above the leaves from position from to position to, both included, level
+// by level up to the root.
+func (t *StoredTree) recompute(from, to int) error {
+	height := t.config.treeHeight(t.size)
+
+	for level, width := 0, t.size; level < height; level, width = level+1, (width+1)/2 {
+		for index := from / 2; index <= to/2; index++ {
+			hash, err := t.parentHash(level, index, width)
+			if err != nil {
+				return err
+			}
+
+			if err := t.nodes.Put(level+1, index, hash); err != nil {
+				return err
+			}
+		}
+
+		from, to = from/2, to/2
+	}
+
+	root, err := t.nodes.Get(height, 0)
+	if err != nil {
+		return err
+	}
+
+	t.MerkleRootHash = root
+
+	return nil
+}
+
+// parentHash calculates the hash of the node at position index of the level above the given level of width
+// nodes. The last node of an odd level is completed according to the odd node strategy of the tree.
+func (t *StoredTree) parentHash(level, index, width int) ([]byte, error) {
+	left, err := t.nodes.Get(level, 2*index)
+	if err != nil {
+		return nil, err
+	}
+
+	if 2*index+1 < width {
+		right, err := t.nodes.Get(level, 2*index+1)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.config.hashChildren(t.HashFunc, left, right)
+	}
+
+	switch t.config.oddNodeStrategy {
+	case OddNodePromote:
+		return left, nil
+	case OddNodeZeroPad:
+		zeroHash, err := t.config.zeroHash(t.HashFunc, level)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.config.hashChildren(t.HashFunc, left, zeroHash)
+	default:
+		return t.config.hashChildren(t.HashFunc, left, left)
+	}
+}
@@ -0,0 +1,278 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestStoredTree(t *testing.T) {
+	payloads := inputs[0].payloads
+
+	for _, strategy := range oddNodeStrategies {
+		for _, cacheSize := range []int{0, 3, merkletree.DefaultCacheSize} {
+			opts := []merkletree.Option{merkletree.WithOddNodeStrategy(strategy), merkletree.WithCacheSize(cacheSize)}
+
+			stored, err := merkletree.NewStoredTree(merkletree.NewMemoryNodeStore(), merkletree.SHA256(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for size := 1; size <= len(payloads); size++ {
+				if err := stored.Append(payloads[size-1]); err != nil {
+					t.Fatal(err)
+				}
+
+				tree, err := merkletree.NewTree(payloads[:size], merkletree.SHA256(), opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				assertStoredTreeEquals(t, stored, tree, payloads[:size], opts)
+			}
+		}
+	}
+}
+
+func TestStoredTreeUpdates(t *testing.T) {
+	payloads := append([]merkletree.Payload{}, inputs[0].payloads...)
+	opts := []merkletree.Option{merkletree.WithRFC6962()}
+
+	stored, err := merkletree.NewStoredTree(merkletree.NewMemoryNodeStore(), merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stored.Append(payloads[:3]...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stored.Append(payloads[3:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads[2], payloads[7] = inputs[1].invalidPayload, inputs[2].invalidPayload
+
+	if err := stored.UpdateLeaf(2, payloads[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stored.UpdateLeaf(7, payloads[7]); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := merkletree.NewTree(payloads, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStoredTreeEquals(t, stored, tree, payloads, opts)
+
+	var rangeErr *merkletree.IndexOutOfRangeError
+	if err := stored.UpdateLeaf(len(payloads), payloads[0]); !errors.As(err, &rangeErr) {
+		t.Errorf("error: expected IndexOutOfRangeError got %v", err)
+	}
+
+	if _, err := merkletree.NewStoredTree(merkletree.NewMemoryNodeStore(), merkletree.SHA256(),
+		merkletree.WithSortedLeaves()); err == nil {
+		t.Error("error: expected error for sorted stored tree")
+	}
+}
+
+func TestStoredTreeProofCopies(t *testing.T) {
+	payloads := inputs[0].payloads
+
+	for _, cacheSize := range []int{0, merkletree.DefaultCacheSize} {
+		opts := []merkletree.Option{merkletree.WithCacheSize(cacheSize)}
+
+		store := merkletree.NewMemoryNodeStore()
+
+		stored, err := merkletree.NewStoredTree(store, merkletree.SHA256(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stored.Append(payloads...); err != nil {
+			t.Fatal(err)
+		}
+
+		tree, err := merkletree.NewTree(payloads, merkletree.SHA256(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Changing a returned proof or root leaves the hashes of the tree unchanged.
+		for i := range payloads {
+			proof, err := stored.ProofByIndex(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proof.LeafHash[0] ^= 0x01
+
+			for _, sibling := range proof.Siblings {
+				sibling[0] ^= 0x01
+			}
+		}
+
+		assertStoredTreeEquals(t, stored, tree, payloads, opts)
+
+		stored.MerkleRootHash[0] ^= 0x01
+
+		reopened, err := merkletree.OpenStoredTree(store, merkletree.SHA256(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertStoredTreeEquals(t, reopened, tree, payloads, opts)
+	}
+}
+
+func TestStoredTreeFileNodeStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nodes")
+	payloads := inputs[0].payloads
+
+	store, err := merkletree.OpenFileNodeStore(dir, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := merkletree.NewStoredTree(store, merkletree.SHA256(), merkletree.WithCacheSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stored.Append(payloads[:5]...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = merkletree.OpenFileNodeStore(dir, sha256.Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := merkletree.NewStoredTree(store, merkletree.SHA256()); err == nil {
+		t.Error("error: expected error for store already holding a tree")
+	}
+
+	reopened, err := merkletree.OpenStoredTree(store, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reopened.Size() != 5 || !bytes.Equal(reopened.MerkleRootHash, stored.MerkleRootHash) {
+		t.Errorf("error: expected reopened tree of 5 leaves with root %x got %d leaves with root %x",
+			stored.MerkleRootHash, reopened.Size(), reopened.MerkleRootHash)
+	}
+
+	if err := reopened.Append(payloads[5:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := merkletree.NewTree(payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStoredTreeEquals(t, reopened, tree, payloads, nil)
+
+	// Updates append the nodes to the log, the index files keep their sizes.
+	sizes := storeFileSizes(t, dir)
+	updated := append([]merkletree.Payload{}, payloads...)
+
+	for i := 0; i < 10; i++ {
+		updated[i%len(payloads)] = payloads[(i+1)%len(payloads)]
+
+		if err := reopened.UpdateLeaf(i%len(payloads), updated[i%len(payloads)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updatedSizes := storeFileSizes(t, dir)
+	if updatedSizes["log"] <= sizes["log"] {
+		t.Errorf("error: expected log to grow from %d bytes got %d", sizes["log"], updatedSizes["log"])
+	}
+
+	delete(sizes, "log")
+	delete(updatedSizes, "log")
+
+	if !reflect.DeepEqual(updatedSizes, sizes) {
+		t.Errorf("error: expected index files of sizes %v got %v", sizes, updatedSizes)
+	}
+
+	tree, err = merkletree.NewTree(updated, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStoredTreeEquals(t, reopened, tree, updated, nil)
+
+	if _, err := merkletree.OpenFileNodeStore(dir, sha256.Size+1); err == nil {
+		t.Error("error: expected error for store opened for another hash size")
+	}
+}
+
+func storeFileSizes(t *testing.T, dir string) map[string]int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := make(map[string]int64, len(entries))
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sizes[e.Name()] = info.Size()
+	}
+
+	return sizes
+}
+
+func assertStoredTreeEquals(
+	t *testing.T, stored *merkletree.StoredTree, tree *merkletree.MerkleTree, payloads []merkletree.Payload,
+	opts []merkletree.Option) {
+	t.Helper()
+
+	if !bytes.Equal(stored.MerkleRootHash, tree.MerkleRootHash) {
+		t.Fatalf("[size: %d] error: expected hash equal to %x got %x",
+			len(payloads), tree.MerkleRootHash, stored.MerkleRootHash)
+	}
+
+	for i, payload := range payloads {
+		proof, err := stored.ProofByIndex(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := tree.ProofByIndex(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(proof.LeafHash, expected.LeafHash) || len(proof.Siblings) != len(expected.Siblings) {
+			t.Errorf("[size: %d] error: expected proof of leaf %d equal to %v got %v", len(payloads), i, expected, proof)
+		}
+
+		verifyProof(t, "stored", stored.MerkleRootHash, payload, *proof, true, opts...)
+	}
+}
@@ -0,0 +1,168 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func paymentTransactions(pp []merkletree.Payload) []merkletree.PaymentTransactionPayload {
+	txs := make([]merkletree.PaymentTransactionPayload, 0, len(pp))
+
+	for _, p := range pp {
+		txs = append(txs, p.(merkletree.PaymentTransactionPayload))
+	}
+
+	return txs
+}
+
+func TestNewTreeTyped(t *testing.T) {
+	for _, test := range inputs {
+		txs := paymentTransactions(test.payloads)
+
+		tree, err := merkletree.NewTree(txs, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(tree.MerkleRootHash, expected.MerkleRootHash) {
+			t.Errorf("[test case: %s] error: expected hash equal to %x got %x",
+				test.testCaseName, expected.MerkleRootHash, tree.MerkleRootHash)
+		}
+
+		if !reflect.DeepEqual(tree.Items(), txs) {
+			t.Errorf("[test case: %s] error: expected items %v got %v", test.testCaseName, txs, tree.Items())
+		}
+
+		for i, tx := range txs {
+			leaf, err := tree.Leaf(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if leaf != tx {
+				t.Errorf("[test case: %s] error: expected leaf %d equal to %v got %v", test.testCaseName, i, tx, leaf)
+			}
+
+			index, err := tree.IndexOf(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proof, err := tree.GetProof(tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proof.LeafIndex != index {
+				t.Errorf("[test case: %s] error: expected proof of leaf %d got %d", test.testCaseName, index, proof.LeafIndex)
+			}
+
+			verifyProof(t, test.testCaseName, tree.MerkleRootHash, tx, *proof, true)
+		}
+
+		var rangeErr *merkletree.IndexOutOfRangeError
+		if _, err := tree.Leaf(len(txs)); !errors.As(err, &rangeErr) {
+			t.Errorf("[test case: %s] error: expected IndexOutOfRangeError got %v", test.testCaseName, err)
+		}
+	}
+}
+
+func TestTreeTypedMultiProof(t *testing.T) {
+	txs := paymentTransactions(inputs[0].payloads)
+
+	tree, err := merkletree.NewTree(txs, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proven := []merkletree.PaymentTransactionPayload{txs[5], txs[1], txs[2]}
+
+	proof, err := tree.GetMultiProof(proven)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := merkletree.VerifyMultiProof(tree.MerkleRootHash, proven, *proof, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Error("error: expected typed multiproof to be valid")
+	}
+}
+
+func TestTreeTypedUpdates(t *testing.T) {
+	txs := paymentTransactions(inputs[0].payloads)
+	extra := paymentTransactions([]merkletree.Payload{inputs[1].invalidPayload, inputs[2].invalidPayload})
+
+	tree, err := merkletree.NewTree(txs[:5], merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tree.Append(txs[5:]...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tree.UpdateLeaf(2, extra[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.InsertLeaf(4, extra[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := append([]merkletree.PaymentTransactionPayload{}, txs...)
+	expected[2] = extra[0]
+	expected = append(expected[:4], append([]merkletree.PaymentTransactionPayload{extra[1]}, expected[4:]...)...)
+
+	if !reflect.DeepEqual(tree.Items(), expected) {
+		t.Errorf("error: expected items %v got %v", expected, tree.Items())
+	}
+
+	rebuilt, err := merkletree.NewTree(expected, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, rebuilt.MerkleRootHash) {
+		t.Errorf("error: expected hash equal to %x got %x", rebuilt.MerkleRootHash, tree.MerkleRootHash)
+	}
+
+	if err := tree.RebuildTreeWith(txs); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tree.Items(), txs) {
+		t.Errorf("error: expected items %v got %v", txs, tree.Items())
+	}
+}
+
+func TestTreeBaseTree(t *testing.T) {
+	tree, err := merkletree.NewTree(paymentTransactions(inputs[0].payloads), merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var base *merkletree.BaseTree
+
+	base = tree.Root.Tree
+
+	if base != &tree.BaseTree || tree.Leafs[0].Tree != base {
+		t.Error("error: expected nodes to refer to the base tree of the tree")
+	}
+
+	if !bytes.Equal(base.MerkleRootHash, tree.MerkleRootHash) {
+		t.Errorf("error: expected hash equal to %x got %x", tree.MerkleRootHash, base.MerkleRootHash)
+	}
+}
@@ -0,0 +1,265 @@
+package merkletree
+
+import "errors"
+
+// UpdateLeaf replaces the item of the leaf at the given position. Only the hashes on the path from
+// the leaf to the root are recomputed, using the Parent links of the nodes. Returns an
+// *IndexOutOfRangeError if there is no leaf at the position.
+func (m *Tree[T]) UpdateLeaf(index int, item T) error {
+	return m.UpdateLeaves(map[int]T{index: item})
+}
+
+// UpdateLeaves replaces the items of the leaves at the given positions. Only the hashes on the paths
+// from the leaves to the root are recomputed and ancestors shared by several of the leaves are recomputed
+// once. The tree is left unchanged if any of the positions is out of range or an item cannot be hashed.
+// Returns ErrSortOrder if the leaves of a sorted tree would be put out of order.
+func (m *Tree[T]) UpdateLeaves(updates map[int]T) error {
+	payloads := make(map[int]Payload, len(updates))
+
+	for i, item := range updates {
+		payloads[i] = item
+	}
+
+	return m.updateLeaves(payloads)
+}
+
+// updateLeaves replaces the payloads of the leaves at the given positions, see UpdateLeaves.
+func (m *BaseTree) updateLeaves(updates map[int]Payload) error {
+	size := m.leafCount()
+	hashes := make(map[int][]byte, len(updates))
+
+	for i, p := range updates {
+		if i < 0 || i >= size {
+			return &IndexOutOfRangeError{Index: i, Size: size}
+		}
+
+		hash, err := m.hashLeaf(p)
+		if err != nil {
+			return err
+		}
+
+		hashes[i] = hash
+	}
+
+	if err := m.checkUpdatedSortOrder(updates, hashes); err != nil {
+		return err
+	}
+
+	dirty := make(map[*Node]bool)
+
+	for i, p := range updates {
+		leaf := m.Leafs[i]
+
+		m.unindexLeaf(i)
+		leaf.Payload = p
+		leaf.Hash = hashes[i]
+		m.indexLeaf(i)
+
+		if i == size-1 && len(m.Leafs) > size && m.Leafs[size].isDuplicate {
+			m.Leafs[size].Payload = p
+			m.Leafs[size].Hash = hashes[i]
+		}
+
+		for n := leaf.Parent; n != nil && !dirty[n]; n = n.Parent {
+			dirty[n] = true
+		}
+	}
+
+	if err := m.rehashNodes(m.Root, dirty); err != nil {
+		return err
+	}
+
+	m.MerkleRootHash = m.Root.Hash
+
+	return nil
+}
+
+// checkUpdatedSortOrder checks that the leaves of a sorted tree stay in order when the payloads of the
+// leaves at the given positions are replaced. Returns ErrSortOrder otherwise.
+func (m *BaseTree) checkUpdatedSortOrder(updates map[int]Payload, hashes map[int][]byte) error {
+	if !m.config.sorted {
+		return nil
+	}
+
+	leafNodes := append([]*Node{}, m.Leafs[:m.leafCount()]...)
+
+	for i, p := range updates {
+		leafNodes[i] = &Node{Hash: hashes[i], Payload: p, isLeaf: true, Tree: m}
+	}
+
+	for i := range updates {
+		if err := m.checkSortOrder(leafNodes, i, i+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rehashNodes recomputes the hash of every node marked as dirty in the subtree of n, children first.
+func (m *BaseTree) rehashNodes(n *Node, dirty map[*Node]bool) error {
+	if !dirty[n] {
+		return nil
+	}
+
+	if err := m.rehashNodes(n.Left, dirty); err != nil {
+		return err
+	}
+
+	if n.Right != n.Left {
+		if err := m.rehashNodes(n.Right, dirty); err != nil {
+			return err
+		}
+	}
+
+	hash, err := m.hashChildren(n.Left.Hash, n.Right.Hash)
+	if err != nil {
+		return err
+	}
+
+	n.Hash = hash
+
+	return nil
+}
+
+// Append adds the items as new leaves at the end of the tree. The complete subtrees the existing
+// leaves are made of are reused, so only the nodes on the right edge of the tree are recomputed. The
+// resulting tree is identical to the one NewTree creates from all the items. Returns ErrSortOrder if the
+// leaves of a sorted tree would be put out of order.
+func (m *Tree[T]) Append(items ...T) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	newLeafNodes, err := constructLeafNodes(payloadsOf(items), &m.BaseTree)
+	if err != nil {
+		return err
+	}
+
+	size := m.leafCount()
+	leafNodes := make([]*Node, 0, size+len(newLeafNodes)+1)
+	leafNodes = append(leafNodes, m.Leafs[:size]...)
+	leafNodes = append(leafNodes, newLeafNodes...)
+
+	if err := m.checkSortOrder(leafNodes, size, len(leafNodes)-1); err != nil {
+		return err
+	}
+
+	return m.reindexAndRebuildFrom(size, leafNodes)
+}
+
+// InsertLeaf inserts the item as a new leaf at the given position, shifting the leaves from that position
+// on to the right. Position equal to the number of leaves appends the item. The complete subtrees of the
+// leaves before the position are reused and only the nodes covering later leaves are recomputed. Returns the
+// new merkle root or an *IndexOutOfRangeError if the position is outside of the tree. Returns ErrSortOrder
+// if the leaves of a sorted tree would be put out of order.
+func (m *Tree[T]) InsertLeaf(index int, item T) ([]byte, error) {
+	size := m.leafCount()
+	if index < 0 || index > size {
+		return nil, &IndexOutOfRangeError{Index: index, Size: size}
+	}
+
+	newLeafNodes, err := constructLeafNodes([]Payload{item}, &m.BaseTree)
+	if err != nil {
+		return nil, err
+	}
+
+	leafNodes := make([]*Node, 0, size+2)
+	leafNodes = append(leafNodes, m.Leafs[:index]...)
+	leafNodes = append(leafNodes, newLeafNodes...)
+	leafNodes = append(leafNodes, m.Leafs[index:size]...)
+
+	if err := m.checkSortOrder(leafNodes, index, index+1); err != nil {
+		return nil, err
+	}
+
+	if err := m.reindexAndRebuildFrom(index, leafNodes); err != nil {
+		return nil, err
+	}
+
+	return m.MerkleRootHash, nil
+}
+
+// RemoveLeaf removes the leaf at the given position, shifting the leaves after it to the left. The complete
+// subtrees of the leaves before the position are reused and only the nodes covering later leaves are
+// recomputed. Returns the new merkle root or an *IndexOutOfRangeError if there is no leaf at the position.
+// The last remaining leaf of a tree cannot be removed.
+func (m *BaseTree) RemoveLeaf(index int) ([]byte, error) {
+	size := m.leafCount()
+	if index < 0 || index >= size {
+		return nil, &IndexOutOfRangeError{Index: index, Size: size}
+	}
+
+	if size == 1 {
+		return nil, errors.New("error: cannot remove the only leaf of the tree")
+	}
+
+	leafNodes := make([]*Node, 0, size)
+	leafNodes = append(leafNodes, m.Leafs[:index]...)
+	leafNodes = append(leafNodes, m.Leafs[index+1:size]...)
+
+	if err := m.reindexAndRebuildFrom(index, leafNodes); err != nil {
+		return nil, err
+	}
+
+	return m.MerkleRootHash, nil
+}
+
+// reindexAndRebuildFrom rebuilds the tree like rebuildFrom and updates the leaf index for the leaves from
+// start on.
+func (m *BaseTree) reindexAndRebuildFrom(start int, leafNodes []*Node) error {
+	for i := m.leafCount() - 1; i >= start; i-- {
+		m.unindexLeaf(i)
+	}
+
+	if err := m.rebuildFrom(start, leafNodes); err != nil {
+		return err
+	}
+
+	for i := start; i < m.leafCount(); i++ {
+		m.indexLeaf(i)
+	}
+
+	return nil
+}
+
+// rebuildFrom rebuilds the tree over the given leaf nodes, of which the first start ones have to be the
+// current leaves of the tree in the same order. The complete subtrees these leaves are made of are reused
+// and only the nodes covering later leaves are constructed.
+func (m *BaseTree) rebuildFrom(start int, leafNodes []*Node) error {
+	subtrees := m.completeSubtrees(start)
+	leafNodes = duplicateLastLeafNode(leafNodes, m)
+
+	root, err := constructNonLeafTreeLevelsFromLeafNodes(leafNodes[start:], m, m.hashNodes, 0, subtrees)
+	if err != nil {
+		return err
+	}
+
+	m.Root = root
+	m.Leafs = leafNodes
+	m.MerkleRootHash = root.Hash
+
+	return nil
+}
+
+// completeSubtrees returns the roots of the complete subtrees the first count leaves of the tree are made
+// of, indexed by their height. There is a subtree for each bit set in count.
+func (m *BaseTree) completeSubtrees(count int) []*Node {
+	var subtrees []*Node
+
+	for height := 0; count>>height > 0; height++ {
+		if (count>>height)&1 == 0 {
+			subtrees = append(subtrees, nil)
+			continue
+		}
+
+		n := m.Leafs[((count>>height)-1)<<height]
+		for i := 0; i < height; i++ {
+			n = n.Parent
+		}
+
+		subtrees = append(subtrees, n)
+	}
+
+	return subtrees
+}
@@ -0,0 +1,298 @@
+package merkletree_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	merkletree "github.com/powerslider/merkle-tree"
+)
+
+func TestMerkleTreeUpdateLeaf(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			for i := range test.payloads {
+				tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := tree.UpdateLeaf(i, test.invalidPayload); err != nil {
+					t.Fatal(err)
+				}
+
+				updated := append([]merkletree.Payload{}, test.payloads...)
+				updated[i] = test.invalidPayload
+
+				assertTreeEquals(t, test.testCaseName, tree, updated, opt)
+			}
+		}
+	}
+}
+
+func TestMerkleTreeUpdateLeaves(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			updated := append([]merkletree.Payload{}, test.payloads...)
+			updates := make(map[int]merkletree.Payload)
+
+			for i := 0; i < len(updated); i += 2 {
+				updates[i] = inputs[(i+1)%len(inputs)].invalidPayload
+				updated[i] = updates[i]
+			}
+
+			if err := tree.UpdateLeaves(updates); err != nil {
+				t.Fatal(err)
+			}
+
+			assertTreeEquals(t, test.testCaseName, tree, updated, opt)
+		}
+	}
+}
+
+func TestMerkleTreeUpdateLeafOutOfRange(t *testing.T) {
+	test := inputs[0]
+
+	tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tree.MerkleRootHash
+
+	err = tree.UpdateLeaves(map[int]merkletree.Payload{0: test.invalidPayload, len(test.payloads): test.invalidPayload})
+
+	var rangeErr *merkletree.IndexOutOfRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Errorf("error: expected IndexOutOfRangeError got %v", err)
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, root) {
+		t.Error("error: expected tree to be unchanged")
+	}
+}
+
+// assertTreeEquals checks that the tree is valid, holds exactly the given payloads and has the same root
+// as a tree newly created from them.
+func assertTreeEquals(
+	t *testing.T, testCaseName string, tree *merkletree.MerkleTree, payloads []merkletree.Payload,
+	opts ...merkletree.Option) {
+	t.Helper()
+
+	expected, err := merkletree.NewTree(payloads, merkletree.SHA256(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(tree.MerkleRootHash, expected.MerkleRootHash) {
+		t.Errorf("[test case: %s] error: expected hash equal to %x got %x",
+			testCaseName, expected.MerkleRootHash, tree.MerkleRootHash)
+	}
+
+	ok, err := tree.VerifyTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Errorf("[test case: %s] error: expected tree to be valid", testCaseName)
+	}
+
+	if len(tree.Leafs) != len(expected.Leafs) {
+		t.Errorf("[test case: %s] error: expected %d leaf nodes got %d",
+			testCaseName, len(expected.Leafs), len(tree.Leafs))
+	}
+
+	for _, payload := range payloads {
+		index, err := tree.IndexOf(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedIndex, err := expected.IndexOf(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if index != expectedIndex {
+			t.Errorf("[test case: %s] error: expected index %d got %d", testCaseName, expectedIndex, index)
+		}
+
+		proof, err := tree.ProofByIndex(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ok, err := merkletree.VerifyProof(expected.MerkleRootHash, payload, *proof, merkletree.SHA256(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok {
+			t.Errorf("[test case: %s] error: expected proof of leaf %d to be valid", testCaseName, index)
+		}
+	}
+}
+
+func TestMerkleTreeAppend(t *testing.T) {
+	var payloads []merkletree.Payload
+	for _, test := range inputs {
+		payloads = append(payloads, test.payloads...)
+	}
+
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for size := 1; size < len(payloads); size++ {
+			tree, err := merkletree.NewTree(payloads[:size], merkletree.SHA256(), opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tree.Append(payloads[size:]...); err != nil {
+				t.Fatal(err)
+			}
+
+			assertTreeEquals(t, "append all", tree, payloads, opt)
+		}
+
+		tree, err := merkletree.NewTree(payloads[:1], merkletree.SHA256(), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for size := 2; size <= len(payloads); size++ {
+			if err := tree.Append(payloads[size-1]); err != nil {
+				t.Fatal(err)
+			}
+
+			assertTreeEquals(t, "append one", tree, payloads[:size], opt)
+		}
+	}
+}
+
+func TestMerkleTreeAppendReusesCompleteSubtrees(t *testing.T) {
+	test := inputs[0]
+
+	for _, strategy := range oddNodeStrategies {
+		tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), merkletree.WithOddNodeStrategy(strategy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		root := tree.Root
+
+		if err := tree.Append(test.invalidPayload); err != nil {
+			t.Fatal(err)
+		}
+
+		if tree.Root.Left != root {
+			t.Errorf("error: expected previous root to be reused with strategy %d", strategy)
+		}
+	}
+}
+
+func TestMerkleTreeInsertLeaf(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			for i := 0; i <= len(test.payloads); i++ {
+				tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				root, err := tree.InsertLeaf(i, test.invalidPayload)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(root, tree.MerkleRootHash) {
+					t.Errorf("[test case: %s] error: expected new root to be returned", test.testCaseName)
+				}
+
+				inserted := append([]merkletree.Payload{}, test.payloads[:i]...)
+				inserted = append(inserted, test.invalidPayload)
+				inserted = append(inserted, test.payloads[i:]...)
+
+				assertTreeEquals(t, test.testCaseName, tree, inserted, opt)
+			}
+		}
+	}
+}
+
+func TestMerkleTreeRemoveLeaf(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+
+		for _, test := range inputs {
+			if len(test.payloads) == 1 {
+				continue
+			}
+
+			for i := range test.payloads {
+				tree, err := merkletree.NewTree(test.payloads, merkletree.SHA256(), opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				root, err := tree.RemoveLeaf(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(root, tree.MerkleRootHash) {
+					t.Errorf("[test case: %s] error: expected new root to be returned", test.testCaseName)
+				}
+
+				removed := append([]merkletree.Payload{}, test.payloads[:i]...)
+				removed = append(removed, test.payloads[i+1:]...)
+
+				assertTreeEquals(t, test.testCaseName, tree, removed, opt)
+
+				if _, err := tree.IndexOf(test.payloads[i]); !errors.Is(err, merkletree.ErrPayloadNotFound) {
+					t.Errorf("[test case: %s] error: expected removed payload not to be found", test.testCaseName)
+				}
+			}
+		}
+	}
+}
+
+func TestMerkleTreeRemoveLeafUntilOneIsLeft(t *testing.T) {
+	for _, strategy := range oddNodeStrategies {
+		opt := merkletree.WithOddNodeStrategy(strategy)
+		payloads := inputs[0].payloads
+
+		tree, err := merkletree.NewTree(payloads, merkletree.SHA256(), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for len(payloads) > 1 {
+			if _, err := tree.RemoveLeaf(len(payloads) / 2); err != nil {
+				t.Fatal(err)
+			}
+
+			payloads = append(append([]merkletree.Payload{}, payloads[:len(payloads)/2]...), payloads[len(payloads)/2+1:]...)
+			assertTreeEquals(t, "remove", tree, payloads, opt)
+		}
+
+		if _, err := tree.RemoveLeaf(0); err == nil {
+			t.Error("error: expected error when removing the only leaf")
+		}
+
+		if _, err := tree.InsertLeaf(2, inputs[0].invalidPayload); err == nil {
+			t.Error("error: expected error when inserting out of range")
+		}
+	}
+}